@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+const (
+	// maxClockSkew 允许的最大时钟偏差
+	maxClockSkew = 30 * time.Second
+
+	// slowLatency 超过该值时提示网络延迟较高
+	slowLatency = time.Second
+)
+
+// checkStatus 检查结果
+type checkStatus string
+
+const (
+	statusOK   checkStatus = "OK"
+	statusWarn checkStatus = "WARN"
+	statusFail checkStatus = "FAIL"
+)
+
+// checkResult 单项检查结果
+type checkResult struct {
+	name   string
+	status checkStatus
+	detail string
+	advice string // 处理建议，检查通过时为空
+}
+
+// doctor 诊断上下文
+type doctor struct {
+	gateway *url.URL
+	timeout time.Duration
+	client  *sto.Client
+	probe   string // 用于验证凭证的运单号
+
+	// 与客户端的WithResolver、WithStaticHosts、WithPinnedCertificates、WithMinTLSVersion对应，
+	// 使网络检查与实际客户端的连接方式一致
+	resolver    *net.Resolver
+	staticHosts map[string][]string
+	pins        []string
+	minTLS      uint16
+}
+
+// stringList 可重复指定的字符串参数
+type stringList []string
+
+// String 实现flag.Value接口
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set 实现flag.Value接口
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// parseStaticHosts 解析"域名=IP[,IP]"形式的静态解析参数
+func parseStaticHosts(entries []string) (map[string][]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	hosts := make(map[string][]string, len(entries))
+	for _, e := range entries {
+		host, ips, ok := strings.Cut(e, "=")
+		if !ok || host == "" || ips == "" {
+			return nil, fmt.Errorf("invalid -static-host %q, want host=ip[,ip]", e)
+		}
+		for _, ip := range strings.Split(ips, ",") {
+			if net.ParseIP(ip) == nil {
+				return nil, fmt.Errorf("invalid -static-host %q: %q is not an ip", e, ip)
+			}
+			hosts[host] = append(hosts[host], ip)
+		}
+	}
+	return hosts, nil
+}
+
+// parseTLSVersion 解析"1.2"、"1.3"形式的TLS版本，空字符串返回0
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("invalid -min-tls %q, want 1.2 or 1.3", v)
+}
+
+// goResolver 返回使用指定DNS服务器的解析器
+func goResolver(server string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// runDoctor 执行 sto doctor，返回进程退出码
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	appKey := fs.String("app-key", os.Getenv("STO_APP_KEY"), "APP KEY，默认读取环境变量 STO_APP_KEY")
+	appSecret := fs.String("app-secret", os.Getenv("STO_APP_SECRET"), "APP SECRET，默认读取环境变量 STO_APP_SECRET")
+	fromCode := fs.String("from-code", os.Getenv("STO_FROM_CODE"), "商户编码，默认读取环境变量 STO_FROM_CODE")
+	probe := fs.String("waybill", "000000000000", "用于验证凭证的运单号，不要求真实存在")
+	timeout := fs.Duration("timeout", 10*time.Second, "单项检查的超时时间")
+	dnsServer := fs.String("dns-server", "", "解析网关域名使用的 DNS 服务器，对应 WithResolver")
+	minTLS := fs.String("min-tls", "", "允许的最低 TLS 版本（1.2 或 1.3），对应 WithMinTLSVersion")
+	var staticHosts, pins stringList
+	fs.Var(&staticHosts, "static-host", "静态解析，格式 host=ip[,ip]，可重复指定，对应 WithStaticHosts")
+	fs.Var(&pins, "pin", "固定的网关证书指纹 sha256/<base64>，可重复指定，对应 WithPinnedCertificates")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	gateway, err := url.Parse(sto.BaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid gateway url: %v\n", err)
+		return 1
+	}
+
+	d := &doctor{
+		gateway: gateway,
+		timeout: *timeout,
+		probe:   *probe,
+		pins:    pins,
+	}
+	if d.staticHosts, err = parseStaticHosts(staticHosts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if d.minTLS, err = parseTLSVersion(*minTLS); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *dnsServer != "" {
+		d.resolver = goResolver(*dnsServer)
+	}
+
+	if *appKey != "" && *appSecret != "" {
+		code := *fromCode
+		if code == "" {
+			code = *appKey
+		}
+		opts := []sto.ClientOption{
+			sto.WithTimeout(*timeout),
+			sto.WithMaxRetries(0),
+		}
+		if d.resolver != nil {
+			opts = append(opts, sto.WithResolver(d.resolver))
+		}
+		if d.staticHosts != nil {
+			opts = append(opts, sto.WithStaticHosts(d.staticHosts))
+		}
+		if d.minTLS != 0 {
+			opts = append(opts, sto.WithMinTLSVersion(d.minTLS))
+		}
+		if len(d.pins) > 0 {
+			opts = append(opts, sto.WithPinnedCertificates(d.pins...))
+		}
+		d.client = sto.NewClient(*appKey, *appSecret, code, opts...)
+	}
+
+	fmt.Printf("gateway: %s\n\n", sto.BaseURL)
+
+	failed := false
+	for _, check := range []func() checkResult{d.checkDNS, d.checkTLS, d.checkHTTP, d.checkCredentials} {
+		r := check()
+		fmt.Printf("[%-4s] %s: %s\n", r.status, r.name, r.detail)
+		if r.advice != "" {
+			fmt.Printf("       -> %s\n", r.advice)
+		}
+		if r.status == statusFail {
+			failed = true
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// checkDNS 检查网关域名解析
+func (d *doctor) checkDNS() checkResult {
+	r := checkResult{name: "DNS"}
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, source, err := d.lookup(ctx, d.gateway.Hostname())
+	if err != nil {
+		r.status = statusFail
+		r.detail = err.Error()
+		r.advice = "检查 DNS 配置，或确认出口网络允许访问申通网关"
+		return r
+	}
+	r.status = statusOK
+	r.detail = fmt.Sprintf("%s -> %v via %s (%s)", d.gateway.Hostname(), addrs, source, time.Since(start).Round(time.Millisecond))
+	return r
+}
+
+// lookup 按客户端的规则解析网关域名：静态解析优先，其次是指定的DNS服务器，最后是系统解析器
+func (d *doctor) lookup(ctx context.Context, host string) (addrs []string, source string, err error) {
+	if ips, ok := d.staticHosts[host]; ok {
+		return ips, "static hosts", nil
+	}
+	if d.resolver != nil {
+		addrs, err = d.resolver.LookupHost(ctx, host)
+		return addrs, "dns server", err
+	}
+	addrs, err = net.DefaultResolver.LookupHost(ctx, host)
+	return addrs, "system resolver", err
+}
+
+// dialContext 连接网关，网关域名按lookup的结果依次尝试各个地址
+func (d *doctor) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: d.timeout}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != d.gateway.Hostname() {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	addrs, _, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address for host %s", host)
+	}
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// tlsConfig 返回与客户端一致的TLS配置，证书固定在checkTLS中单独检查
+func (d *doctor) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: d.gateway.Hostname(), MinVersion: d.minTLS}
+}
+
+// matchPin 返回证书链中匹配固定指纹的证书指纹，没有匹配时返回空字符串
+func (d *doctor) matchPin(state tls.ConnectionState) string {
+	chains := state.VerifiedChains
+	if len(chains) == 0 && len(state.PeerCertificates) > 0 {
+		// 未校验证书链时只匹配叶子证书，其后的证书可由服务端任意附带
+		chains = [][]*x509.Certificate{state.PeerCertificates[:1]}
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			pin := sto.CertificatePin(cert)
+			for _, p := range d.pins {
+				if p == pin {
+					return pin
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// checkTLS 检查TLS握手和证书有效期
+func (d *doctor) checkTLS() checkResult {
+	r := checkResult{name: "TLS"}
+	host := d.gateway.Hostname()
+	port := d.gateway.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	raw, err := d.dialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		r.status = statusFail
+		r.detail = err.Error()
+		r.advice = "检查防火墙是否放行 443 端口，以及是否有代理拦截 HTTPS 流量"
+		return r
+	}
+	conn := tls.Client(raw, d.tlsConfig())
+	defer conn.Close()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		r.status = statusFail
+		r.detail = err.Error()
+		r.advice = "检查是否有代理拦截 HTTPS 流量，系统根证书是否需要更新；指定了 -min-tls 时确认网关支持该版本"
+		return r
+	}
+
+	state := conn.ConnectionState()
+	cert := state.PeerCertificates[0]
+	r.status = statusOK
+	r.detail = fmt.Sprintf("%s, certificate %s expires %s, pin %s",
+		tls.VersionName(state.Version), cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"), sto.CertificatePin(cert))
+	if len(d.pins) > 0 {
+		if pin := d.matchPin(state); pin != "" {
+			r.detail += ", pinned " + pin
+		} else {
+			r.status = statusFail
+			r.advice = "网关证书与 -pin 指定的指纹都不匹配，客户端开启 WithPinnedCertificates 后将无法连接；确认指纹是否已随证书轮换更新"
+			return r
+		}
+	}
+	if time.Until(cert.NotAfter) < 14*24*time.Hour {
+		r.status = statusWarn
+		r.advice = "网关证书即将过期，如遇证书错误请联系申通技术支持"
+	}
+	return r
+}
+
+// checkHTTP 检查网关延迟和本机时钟偏差
+func (d *doctor) checkHTTP() checkResult {
+	r := checkResult{name: "Latency/Clock"}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = d.dialContext
+	transport.TLSClientConfig = d.tlsConfig()
+	client := &http.Client{Timeout: d.timeout, Transport: transport}
+
+	start := time.Now()
+	resp, err := client.Head(sto.BaseURL)
+	latency := time.Since(start)
+	if err != nil {
+		r.status = statusFail
+		r.detail = err.Error()
+		r.advice = "网关无法访问，检查代理设置和出口网络"
+		return r
+	}
+	resp.Body.Close()
+
+	r.status = statusOK
+	r.detail = fmt.Sprintf("latency %s", latency.Round(time.Millisecond))
+	if latency > slowLatency {
+		r.status = statusWarn
+		r.advice = "网络延迟较高，建议适当调大 WithTimeout 并开启 WithSlowRequestThreshold 观察"
+	}
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		r.detail += ", server time unavailable"
+		return r
+	}
+	// Date头精度为秒，按请求中点估算
+	skew := time.Until(serverTime.Add(latency / 2))
+	r.detail += fmt.Sprintf(", clock skew %s", skew.Round(time.Second))
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		r.status = statusFail
+		r.advice = "本机时钟偏差过大，请配置 NTP 同步时间"
+	}
+	return r
+}
+
+// checkCredentials 通过一次轨迹查询验证APP KEY、APP SECRET和接口权限
+func (d *doctor) checkCredentials() checkResult {
+	r := checkResult{name: "Credentials"}
+	if d.client == nil {
+		r.status = statusWarn
+		r.detail = "skipped, app key or app secret not provided"
+		r.advice = "通过 -app-key/-app-secret 参数或 STO_APP_KEY/STO_APP_SECRET 环境变量提供凭证"
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := d.client.QueryTraceContext(ctx, &sto.TraceQueryRequest{WaybillNoList: []string{d.probe}})
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		r.status = statusFail
+		r.detail = err.Error()
+		var apiErr *sto.APIError
+		if errors.As(err, &apiErr) && apiErr.NeedRetry {
+			r.advice = "网关繁忙，请稍后重试"
+		} else {
+			r.advice = "请求网关失败，参考上面的网络检查结果"
+		}
+		return r
+	}
+
+	if resp.IsSuccess() {
+		r.status = statusOK
+		r.detail = fmt.Sprintf("signature accepted (%s)", latency)
+		return r
+	}
+
+	switch resp.ErrorCode {
+	case sto.ErrorCodeWaybillInvalid:
+		// 运单号错误说明签名和权限校验已通过
+		r.status = statusOK
+		r.detail = fmt.Sprintf("signature accepted (%s)", latency)
+	case sto.ErrorCodeNoPermission:
+		r.status = statusFail
+		r.detail = fmt.Sprintf("%s - %s (requestId %s)", resp.ErrorCode, resp.ErrorMsg, resp.RequestId)
+		r.advice = "无接口权限：确认 APP KEY 正确，且已在开放平台申请轨迹查询接口权限"
+	case sto.ErrorCodeSignature:
+		r.status = statusFail
+		r.detail = fmt.Sprintf("%s - %s (requestId %s)", resp.ErrorCode, resp.ErrorMsg, resp.RequestId)
+		r.advice = "签名错误：确认 APP SECRET 正确，且没有多余的空格或换行"
+	default:
+		// 其他错误无法确认凭证是否有效
+		r.status = statusWarn
+		r.detail = fmt.Sprintf("%s - %s (requestId %s)", resp.ErrorCode, resp.ErrorMsg, resp.RequestId)
+		r.advice = "无法确认凭证是否有效，参考 sto error-codes 中该错误码的说明，必要时使用 -waybill 指定真实运单号重试"
+	}
+	return r
+}
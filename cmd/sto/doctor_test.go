@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want checkStatus
+	}{
+		{"success", nil, statusOK},
+		{"waybill invalid", &sto.APIError{ErrorCode: sto.ErrorCodeWaybillInvalid}, statusOK},
+		{"no permission", &sto.APIError{ErrorCode: sto.ErrorCodeNoPermission}, statusFail},
+		{"signature", &sto.APIError{ErrorCode: sto.ErrorCodeSignature}, statusFail},
+		{"busy", &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, NeedRetry: true}, statusFail},
+		{"unknown code", &sto.APIError{ErrorCode: "E999", ErrorMsg: "unexpected"}, statusWarn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := stotest.NewGateway("secret")
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return map[string][]sto.TraceInfo{}, nil
+			})
+			srv := stotest.NewServer(gw)
+			defer srv.Close()
+
+			d := &doctor{
+				timeout: 5 * time.Second,
+				probe:   "000000000000",
+				client: sto.NewClient("key", "secret", "code",
+					sto.WithBaseURL(srv.URL),
+					sto.WithHTTPClient(srv.Client()),
+					sto.WithMaxRetries(0),
+				),
+			}
+			if r := d.checkCredentials(); r.status != tt.want {
+				t.Errorf("status = %s (%s), want %s", r.status, r.detail, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStaticHosts(t *testing.T) {
+	tests := []struct {
+		in      []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{nil, nil, false},
+		{[]string{"gw.example=203.0.113.10"}, map[string][]string{"gw.example": {"203.0.113.10"}}, false},
+		{[]string{"gw.example=203.0.113.10,203.0.113.11"}, map[string][]string{"gw.example": {"203.0.113.10", "203.0.113.11"}}, false},
+		{[]string{"gw.example"}, nil, true},
+		{[]string{"gw.example=not-an-ip"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseStaticHosts(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStaticHosts(%v) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseStaticHosts(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPin(t *testing.T) {
+	srv := stotest.NewServer(stotest.NewGateway("secret"))
+	defer srv.Close()
+	cert := srv.Certificate()
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	// 未校验的证书链中叶子之后附带了固定的证书，不能视为匹配
+	foreign := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("foreign key")}
+	appended := []*x509.Certificate{foreign, cert}
+
+	tests := []struct {
+		name  string
+		state tls.ConnectionState
+		pins  []string
+		want  string
+	}{
+		{"match", state, []string{"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", sto.CertificatePin(cert)}, sto.CertificatePin(cert)},
+		{"mismatch", state, []string{"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, ""},
+		{"pinned certificate appended to foreign leaf", tls.ConnectionState{PeerCertificates: appended}, []string{sto.CertificatePin(cert)}, ""},
+		{"pinned certificate in verified chain", tls.ConnectionState{PeerCertificates: appended, VerifiedChains: [][]*x509.Certificate{appended}}, []string{sto.CertificatePin(cert)}, sto.CertificatePin(cert)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &doctor{pins: tt.pins}
+			if got := d.matchPin(tt.state); got != tt.want {
+				t.Errorf("matchPin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Command sto 申通开放平台命令行工具
+//
+// 用法：
+//
+//	sto doctor [flags]    检查网络、证书、凭证、延迟和时钟偏差
+//	sto error-codes       以JSON格式输出全部错误码，用于生成监控面板和告警规则
+//
+// 凭证可以通过参数或环境变量 STO_APP_KEY、STO_APP_SECRET、STO_FROM_CODE 提供。
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var code int
+	switch os.Args[1] {
+	case "doctor":
+		code = runDoctor(os.Args[2:])
+	case "error-codes":
+		code = runErrorCodes(os.Stdout)
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", os.Args[1])
+		usage()
+		code = 2
+	}
+	os.Exit(code)
+}
+
+// usage 打印用法
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: sto <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  doctor         检查网络、证书、凭证、延迟和时钟偏差")
+	fmt.Fprintln(os.Stderr, "  error-codes    以JSON格式输出全部错误码")
+}
+
+// runErrorCodes 将错误码目录输出到w
+func runErrorCodes(w io.Writer) int {
+	b, err := sto.ErrorCatalogJSON()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintln(w, string(b))
+	return 0
+}
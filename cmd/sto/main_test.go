@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+func TestRunErrorCodes(t *testing.T) {
+	var out bytes.Buffer
+	if code := runErrorCodes(&out); code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+
+	// 错误码本身由sto包的golden文件固定，这里只检查输出与错误码目录一致
+	want, err := sto.ErrorCatalogJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), append(want, '\n')) {
+		t.Errorf("sto error-codes output differs from sto.ErrorCatalogJSON:\ngot:\n%s", out.Bytes())
+	}
+}
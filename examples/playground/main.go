@@ -0,0 +1,126 @@
+// playground 是本地开发用的示例服务：启动模拟网关并生成一批模拟运单，
+// 通过SDK查询轨迹并以JSON接口提供给前端，无需真实运单即可开发和测试物流跟踪页面。
+//
+//	go run ./examples/playground -addr :8080 -waybills 50 -speed 60
+//
+// 接口：
+//
+//	GET /api/waybills                 模拟运单列表及其场景
+//	GET /api/track?waybillNo=运单号    运单当前节点和轨迹
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+const (
+	appKey    = "playground"
+	appSecret = "playground-secret"
+	fromCode  = "playground"
+)
+
+// waybill 模拟运单
+type waybill struct {
+	WaybillNo string           `json:"waybillNo"`
+	Scenario  stotest.Scenario `json:"scenario"`
+}
+
+// trackResult 轨迹查询结果
+type trackResult struct {
+	WaybillNo string          `json:"waybillNo"`
+	Milestone sto.Milestone   `json:"milestone"`
+	Traces    []sto.TraceInfo `json:"traces"`
+}
+
+// indexPage 运单列表页面
+var indexPage = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>STO Playground</title></head>
+<body>
+<h1>模拟运单</h1>
+<table>
+<tr><th>运单号</th><th>场景</th></tr>
+{{range .}}<tr><td><a href="/api/track?waybillNo={{.WaybillNo}}">{{.WaybillNo}}</a></td><td>{{.Scenario}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+func main() {
+	addr := flag.String("addr", ":8080", "监听地址")
+	count := flag.Int("waybills", 50, "生成的模拟运单数")
+	seed := flag.Int64("seed", 1, "随机种子，相同种子生成相同的数据")
+	speed := flag.Float64("speed", 1, "模拟时钟的速度倍数，大于1时轨迹按倍速推进")
+	flag.Parse()
+
+	gw := stotest.NewGateway(appSecret)
+	started := time.Now()
+	gw.SetClock(func() time.Time {
+		return started.Add(time.Duration(float64(time.Since(started)) * *speed))
+	})
+
+	gen := stotest.NewGenerator(*seed)
+	waybills := make([]waybill, 0, *count)
+	for i := 0; i < *count; i++ {
+		w := waybill{WaybillNo: gen.WaybillNo(), Scenario: gen.Scenario()}
+		// 揽收时间分布在过去5天内，部分运单的后续轨迹会随模拟时钟陆续出现
+		start := started.Add(-time.Duration(i%5*24+i%24) * time.Hour)
+		gw.SetTraces(w.WaybillNo, gen.Traces(w.WaybillNo, w.Scenario, start))
+		waybills = append(waybills, w)
+	}
+
+	srv := stotest.NewServer(gw)
+	defer srv.Close()
+
+	client := sto.NewClient(appKey, appSecret, fromCode,
+		sto.WithBaseURL(srv.URL),
+		sto.WithHTTPClient(srv.Client()),
+	)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		indexPage.Execute(w, waybills)
+	})
+	http.HandleFunc("/api/waybills", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, waybills)
+	})
+	http.HandleFunc("/api/track", func(w http.ResponseWriter, r *http.Request) {
+		no := r.URL.Query().Get("waybillNo")
+		resp, err := client.QueryTraceContext(r.Context(), &sto.TraceQueryRequest{
+			Order:         "asc",
+			WaybillNoList: []string{no},
+		})
+		if err == nil {
+			err = resp.Err()
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		traces := resp.Data[no]
+		writeJSON(w, http.StatusOK, trackResult{
+			WaybillNo: no,
+			Milestone: sto.CurrentMilestone(traces),
+			Traces:    traces,
+		})
+	})
+
+	fmt.Printf("mock gateway: %s\n", srv.URL)
+	fmt.Printf("playground:   http://localhost%s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// writeJSON 写入JSON响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,247 @@
+package sto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// amountScale 金额精度，Amount以万分之一元为单位
+const amountScale = 10000
+
+// Amount 定点小数表示的金额，精确到4位小数，用于运费、代收货款等字段，避免float64的舍入误差。
+// 零值表示0元，取值范围为±922337203685477.5807元，超出范围的运算会panic
+type Amount struct {
+	units int64 // 万分之一元
+}
+
+// AmountCodec 金额的JSON编解码选项
+type AmountCodec struct {
+	EncodeAsString bool // 请求中的金额序列化为JSON字符串（如"12.50"），默认序列化为JSON数字
+	Strict         bool // 响应中的金额超过4位小数时返回错误，默认四舍五入
+}
+
+// WithAmountCodec 设置客户端请求和响应中金额字段的编解码方式
+func WithAmountCodec(codec AmountCodec) ClientOption {
+	return func(c *Client) {
+		c.amountCodec = codec
+	}
+}
+
+// maxAmountUnits 金额的最大绝对值，单位为万分之一元。不使用math.MinInt64，保证取反不溢出
+const maxAmountUnits = math.MaxInt64
+
+// amountType Amount的反射类型
+var amountType = reflect.TypeOf(Amount{})
+
+// isAmountField 是否为Amount类型的字段
+func isAmountField(f reflect.StructField) bool {
+	return f.Type == amountType
+}
+
+// decimalPattern 普通十进制数，不允许分数、指数等形式，小数点两侧至少有一侧是数字且小数点后不能为空
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d+)?|\.\d+)$`)
+
+// NewAmountFromCents 以分为单位创建金额，超出Amount的取值范围时panic
+func NewAmountFromCents(cents int64) Amount {
+	const per = amountScale / 100
+	if cents > maxAmountUnits/per || cents < -maxAmountUnits/per {
+		panic(fmt.Sprintf("sto: amount of %d cents out of range", cents))
+	}
+	return Amount{units: cents * per}
+}
+
+// ParseAmount 解析十进制金额字符串，如"12.5"、"-0.01"，空字符串为0，超过4位小数时四舍五入。
+// 不接受分数（如"1/3"）和指数（如"1e3"）形式
+func ParseAmount(s string) (Amount, error) {
+	return parseAmount(s, false)
+}
+
+// parseAmount 解析十进制金额字符串，strict为true时超过4位小数返回错误
+func parseAmount(s string, strict bool) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Amount{}, nil
+	}
+	if !decimalPattern.MatchString(s) {
+		return Amount{}, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount: %q", s)
+	}
+	r.Mul(r, big.NewRat(amountScale, 1))
+
+	units := new(big.Int)
+	if r.IsInt() {
+		units.Set(r.Num())
+	} else {
+		if strict {
+			return Amount{}, fmt.Errorf("amount %q exceeds 4 decimal places", s)
+		}
+		// 四舍五入（远离零）
+		num, den := new(big.Int).Abs(r.Num()), r.Denom()
+		q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+		if m.Mul(m, big.NewInt(2)).Cmp(den) >= 0 {
+			q.Add(q, big.NewInt(1))
+		}
+		if r.Sign() < 0 {
+			q.Neg(q)
+		}
+		units = q
+	}
+
+	if units.CmpAbs(big.NewInt(maxAmountUnits)) > 0 {
+		return Amount{}, fmt.Errorf("amount %q out of range", s)
+	}
+	return Amount{units: units.Int64()}, nil
+}
+
+// Cents 返回以分为单位的金额，不足一分的部分四舍五入
+func (a Amount) Cents() int64 {
+	const per = amountScale / 100
+	units := a.units
+	if units < 0 {
+		units = -units
+	}
+	cents := units / per
+	if units%per >= per/2 {
+		cents++
+	}
+	if a.units < 0 {
+		return -cents
+	}
+	return cents
+}
+
+// Float64 返回金额的浮点数近似值，仅用于展示
+func (a Amount) Float64() float64 {
+	return float64(a.units) / amountScale
+}
+
+// IsZero 是否为0
+func (a Amount) IsZero() bool {
+	return a.units == 0
+}
+
+// Add 返回a+b，结果超出Amount的取值范围时panic
+func (a Amount) Add(b Amount) Amount {
+	if (b.units > 0 && a.units > maxAmountUnits-b.units) || (b.units < 0 && a.units < -maxAmountUnits-b.units) {
+		panic(fmt.Sprintf("sto: amount %s + %s out of range", a, b))
+	}
+	return Amount{units: a.units + b.units}
+}
+
+// Sub 返回a-b，结果超出Amount的取值范围时panic
+func (a Amount) Sub(b Amount) Amount {
+	return a.Add(Amount{units: -b.units})
+}
+
+// Cmp 比较a和b，a<b返回-1，相等返回0，a>b返回1
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.units < b.units:
+		return -1
+	case a.units > b.units:
+		return 1
+	}
+	return 0
+}
+
+// String 返回十进制表示，至少保留2位小数，如"12.50"、"0.0125"
+func (a Amount) String() string {
+	units := a.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	frac := fmt.Sprintf("%04d", units%amountScale)
+	frac = strings.TrimRight(frac, "0")
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	return sign + strconv.FormatInt(units/amountScale, 10) + "." + frac
+}
+
+// MarshalJSON 实现json.Marshaler接口，序列化为JSON数字。
+// 客户端设置WithAmountCodec时，请求中的金额按其中的选项序列化
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON 实现json.Unmarshaler接口，同时支持JSON数字和字符串，null和空字符串为0
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*a = Amount{}
+		return nil
+	}
+
+	s, err := amountText(data)
+	if err != nil {
+		return err
+	}
+	v, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// amountText 返回JSON数字或字符串形式的金额文本
+func amountText(data []byte) (string, error) {
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return "", fmt.Errorf("invalid amount: %s", data)
+		}
+	}
+	return s, nil
+}
+
+// checkStrictAmounts 按v的类型找到raw中的金额字段，超过4位小数时返回错误
+func checkStrictAmounts(raw []byte, v interface{}) error {
+	paths := fieldPaths(reflect.TypeOf(v), amountFields)
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		// 格式错误由后续的json.Unmarshal报告
+		return nil
+	}
+	for _, path := range paths {
+		err := walkFieldPath(doc, path, func(obj map[string]interface{}, key string) error {
+			var s string
+			switch val := obj[key].(type) {
+			case json.Number:
+				s = val.String()
+			case string:
+				s = val
+			default:
+				return nil
+			}
+			if _, err := parseAmount(s, true); err != nil {
+				return fmt.Errorf("field %s: %v", key, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
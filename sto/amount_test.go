@@ -0,0 +1,188 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantCents int64
+		wantStr   string
+		wantErr   bool
+	}{
+		{"", 0, "0.00", false},
+		{"12.5", 1250, "12.50", false},
+		{" 12.50 ", 1250, "12.50", false},
+		{"-0.01", -1, "-0.01", false},
+		{"+3", 300, "3.00", false},
+		{".5", 50, "0.50", false},
+		{"7.", 0, "", true},
+		{"-.", 0, "", true},
+		{"0.0125", 1, "0.0125", false},
+		{"0.00005", 0, "0.0001", false},
+		{"-0.00005", 0, "-0.0001", false},
+		{"1/3", 0, "", true},
+		{"1e3", 0, "", true},
+		{"1e1000000", 0, "", true},
+		{"0x10", 0, "", true},
+		{"12.5元", 0, "", true},
+		{"1.2.3", 0, "", true},
+		{"-", 0, "", true},
+		{"99999999999999999999", 0, "", true},
+		{"922337203685477.5807", 92233720368547758, "922337203685477.5807", false},
+		{"922337203685477.5808", 0, "", true},
+		{"-922337203685477.5808", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			a, err := sto.ParseAmount(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAmount(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if a.Cents() != tt.wantCents || a.String() != tt.wantStr {
+				t.Errorf("ParseAmount(%q) = %s (%d cents), want %s (%d cents)", tt.in, a, a.Cents(), tt.wantStr, tt.wantCents)
+			}
+		})
+	}
+}
+
+func TestAmountRange(t *testing.T) {
+	max, err := sto.ParseAmount("922337203685477.5807")
+	if err != nil {
+		t.Fatal(err)
+	}
+	min, err := sto.ParseAmount("-922337203685477.5807")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit, _ := sto.ParseAmount("0.0001")
+
+	tests := []struct {
+		name      string
+		fn        func() sto.Amount
+		want      string
+		wantPanic bool
+	}{
+		{"cents", func() sto.Amount { return sto.NewAmountFromCents(-1250) }, "-12.50", false},
+		{"cents at limit", func() sto.Amount { return sto.NewAmountFromCents(92233720368547758) }, "922337203685477.58", false},
+		{"cents overflow", func() sto.Amount { return sto.NewAmountFromCents(92233720368547759) }, "", true},
+		{"cents underflow", func() sto.Amount { return sto.NewAmountFromCents(-92233720368547759) }, "", true},
+		{"add to limit", func() sto.Amount { return max.Sub(unit).Add(unit) }, "922337203685477.5807", false},
+		{"add overflow", func() sto.Amount { return max.Add(unit) }, "", true},
+		{"add underflow", func() sto.Amount { return min.Add(unit.Sub(unit).Sub(unit)) }, "", true},
+		{"sub overflow", func() sto.Amount { return max.Sub(unit.Sub(unit).Sub(unit)) }, "", true},
+		{"sub underflow", func() sto.Amount { return min.Sub(unit) }, "", true},
+		{"opposite signs", func() sto.Amount { return max.Add(min) }, "0.00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+			if got := tt.fn().String(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{`12.5`, "12.50", false},
+		{`"12.5"`, "12.50", false},
+		{`null`, "0.00", false},
+		{`""`, "0.00", false},
+		{`"1/3"`, "", true},
+		{`1e2`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var a sto.Amount
+			err := json.Unmarshal([]byte(tt.in), &a)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && a.String() != tt.want {
+				t.Errorf("Unmarshal(%s) = %s, want %s", tt.in, a, tt.want)
+			}
+		})
+	}
+
+	out, err := json.Marshal(struct{ V sto.Amount }{sto.NewAmountFromCents(1250)})
+	if err != nil || string(out) != `{"V":12.50}` {
+		t.Errorf("Marshal = %s, %v", out, err)
+	}
+}
+
+func TestWithAmountCodec(t *testing.T) {
+	tests := []struct {
+		name        string
+		codec       sto.AmountCodec
+		freight     string
+		wantCod     string
+		wantFreight string
+		wantErr     bool
+	}{
+		{"default", sto.AmountCodec{}, `12.34567`, `8.80`, "12.3457", false},
+		{"encode as string", sto.AmountCodec{EncodeAsString: true}, `12.34567`, `"8.80"`, "12.3457", false},
+		{"strict accepts 4 places", sto.AmountCodec{Strict: true}, `"12.3456"`, `8.80`, "12.3456", false},
+		{"strict rejects 5 places", sto.AmountCodec{Strict: true}, `12.34567`, `8.80`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var codValue json.RawMessage
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("OMS_EXPRESS_ORDER_CREATE", func(content json.RawMessage) (interface{}, error) {
+				var req map[string]json.RawMessage
+				if err := json.Unmarshal(content, &req); err != nil {
+					return nil, err
+				}
+				codValue = req["codValue"]
+				return map[string]string{"orderNo": "O1", "waybillNo": "773000000001"}, nil
+			})
+			gw.Handle("STO_TIME_EFFECT_QUERY", func(json.RawMessage) (interface{}, error) {
+				return json.RawMessage(`{"estimatedHours":24,"freight":` + tt.freight + `}`), nil
+			})
+			client := newTestClient(t, gw, sto.WithAmountCodec(tt.codec))
+
+			order := testOrder("O1")
+			order.CodValue = sto.NewAmountFromCents(880)
+			if _, err := client.CreateOrder(context.Background(), order); err != nil {
+				t.Fatal(err)
+			}
+			if string(codValue) != tt.wantCod {
+				t.Errorf("codValue = %s, want %s", codValue, tt.wantCod)
+			}
+
+			resp, err := client.QueryETA(context.Background(), &sto.ETAQueryRequest{
+				SenderProvince: "上海市", SenderCity: "上海市",
+				ReceiverProvince: "浙江省", ReceiverCity: "杭州市",
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("QueryETA err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && resp.Data.Freight.String() != tt.wantFreight {
+				t.Errorf("freight = %s, want %s", resp.Data.Freight, tt.wantFreight)
+			}
+		})
+	}
+}
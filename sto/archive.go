@@ -0,0 +1,262 @@
+package sto
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// traceArchiveFormat 轨迹归档格式标识
+	traceArchiveFormat = "sto-trace-archive"
+
+	// TraceArchiveVersion 当前的轨迹归档格式版本
+	TraceArchiveVersion = 1
+
+	// traceArchiveMaxLine 单行的最大长度
+	traceArchiveMaxLine = 16 << 20
+)
+
+// traceArchiveHeader 归档文件首行，记录格式版本和字段顺序
+type traceArchiveHeader struct {
+	Format  string   `json:"format"`
+	Version int      `json:"version"`
+	Fields  []string `json:"fields"`
+}
+
+// traceField TraceInfo中参与归档的字段
+type traceField struct {
+	name  string // JSON字段名
+	index int    // 结构体字段下标
+}
+
+// traceFields 按结构体顺序排列的归档字段，不含运单号（每行单独记录）
+var traceFields = func() []traceField {
+	var fields []traceField
+	t := reflect.TypeOf(TraceInfo{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || name == "waybillNo" || t.Field(i).Type.Kind() != reflect.String {
+			continue
+		}
+		fields = append(fields, traceField{name: name, index: i})
+	}
+	return fields
+}()
+
+// TraceArchiveWriter 以JSON Lines格式写入轨迹归档。首行为带版本号和字段顺序的头，
+// 之后每行为一个运单：["运单号",[[字段值...],...]]，每条轨迹只按字段顺序记录值并省略末尾的空值。
+// 需要压缩时可以将gzip.Writer作为w传入
+type TraceArchiveWriter struct {
+	w           *bufio.Writer
+	wroteHeader bool
+}
+
+// NewTraceArchiveWriter 创建轨迹归档写入器
+func NewTraceArchiveWriter(w io.Writer) *TraceArchiveWriter {
+	return &TraceArchiveWriter{w: bufio.NewWriter(w)}
+}
+
+// Write 写入一个运单的轨迹
+func (aw *TraceArchiveWriter) Write(waybillNo string, traces []TraceInfo) error {
+	if err := aw.writeHeader(); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(traces))
+	for i := range traces {
+		v := reflect.ValueOf(traces[i])
+		row := make([]string, len(traceFields))
+		n := 0
+		for j, f := range traceFields {
+			row[j] = v.Field(f.index).String()
+			if row[j] != "" {
+				n = j + 1
+			}
+		}
+		rows[i] = row[:n]
+	}
+	return aw.writeLine([]interface{}{waybillNo, rows})
+}
+
+// writeHeader 写入文件头，只写一次
+func (aw *TraceArchiveWriter) writeHeader() error {
+	if aw.wroteHeader {
+		return nil
+	}
+	header := traceArchiveHeader{Format: traceArchiveFormat, Version: TraceArchiveVersion}
+	for _, f := range traceFields {
+		header.Fields = append(header.Fields, f.name)
+	}
+	if err := aw.writeLine(header); err != nil {
+		return err
+	}
+	aw.wroteHeader = true
+	return nil
+}
+
+// writeLine 写入一行JSON
+func (aw *TraceArchiveWriter) writeLine(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal archive line failed: %v", err)
+	}
+	if _, err := aw.w.Write(b); err != nil {
+		return err
+	}
+	return aw.w.WriteByte('\n')
+}
+
+// Flush 将缓冲的数据写入底层Writer，写入完成后必须调用
+func (aw *TraceArchiveWriter) Flush() error {
+	if err := aw.writeHeader(); err != nil {
+		return err
+	}
+	return aw.w.Flush()
+}
+
+// TraceArchiveReader 读取TraceArchiveWriter写入的轨迹归档，自动识别gzip压缩
+type TraceArchiveReader struct {
+	scanner *bufio.Scanner
+	fields  []int // 归档中各列对应的结构体字段下标，-1表示当前版本不认识的字段
+	gz      *gzip.Reader
+}
+
+// NewTraceArchiveReader 创建轨迹归档读取器并读取文件头
+func NewTraceArchiveReader(r io.Reader) (*TraceArchiveReader, error) {
+	ar := &TraceArchiveReader{}
+
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip archive failed: %v", err)
+		}
+		ar.gz = gz
+		r = gz
+	} else {
+		r = br
+	}
+
+	ar.scanner = bufio.NewScanner(r)
+	ar.scanner.Buffer(make([]byte, 64*1024), traceArchiveMaxLine)
+	if !ar.scanner.Scan() {
+		if err := ar.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty trace archive")
+	}
+
+	var header traceArchiveHeader
+	if err := json.Unmarshal(ar.scanner.Bytes(), &header); err != nil || header.Format != traceArchiveFormat {
+		return nil, fmt.Errorf("not a trace archive")
+	}
+	if header.Version < 1 || header.Version > TraceArchiveVersion {
+		return nil, fmt.Errorf("unsupported trace archive version %d", header.Version)
+	}
+
+	index := make(map[string]int, len(traceFields))
+	for _, f := range traceFields {
+		index[f.name] = f.index
+	}
+	ar.fields = make([]int, len(header.Fields))
+	for i, name := range header.Fields {
+		if idx, ok := index[name]; ok {
+			ar.fields[i] = idx
+		} else {
+			ar.fields[i] = -1
+		}
+	}
+	return ar, nil
+}
+
+// Next 读取下一个运单的轨迹，读取完毕时返回io.EOF
+func (ar *TraceArchiveReader) Next() (string, []TraceInfo, error) {
+	for ar.scanner.Scan() {
+		line := ar.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record []json.RawMessage
+		var waybillNo string
+		var rows [][]string
+		if err := json.Unmarshal(line, &record); err != nil || len(record) != 2 {
+			return "", nil, fmt.Errorf("invalid archive line")
+		}
+		if err := json.Unmarshal(record[0], &waybillNo); err != nil {
+			return "", nil, fmt.Errorf("invalid archive waybillNo: %v", err)
+		}
+		if err := json.Unmarshal(record[1], &rows); err != nil {
+			return "", nil, fmt.Errorf("invalid archive traces: %v", err)
+		}
+
+		traces := make([]TraceInfo, len(rows))
+		for i, row := range rows {
+			traces[i].WaybillNo = waybillNo
+			v := reflect.ValueOf(&traces[i]).Elem()
+			for j, val := range row {
+				if j < len(ar.fields) && ar.fields[j] >= 0 {
+					v.Field(ar.fields[j]).SetString(val)
+				}
+			}
+		}
+		return waybillNo, traces, nil
+	}
+
+	if err := ar.scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	return "", nil, io.EOF
+}
+
+// Close 释放读取器资源，不关闭底层Reader
+func (ar *TraceArchiveReader) Close() error {
+	if ar.gz != nil {
+		return ar.gz.Close()
+	}
+	return nil
+}
+
+// SaveTraces 将多个运单的轨迹写入归档，运单按运单号排序
+func SaveTraces(w io.Writer, data map[string][]TraceInfo) error {
+	waybillNos := make([]string, 0, len(data))
+	for no := range data {
+		waybillNos = append(waybillNos, no)
+	}
+	sort.Strings(waybillNos)
+
+	aw := NewTraceArchiveWriter(w)
+	for _, no := range waybillNos {
+		if err := aw.Write(no, data[no]); err != nil {
+			return err
+		}
+	}
+	return aw.Flush()
+}
+
+// LoadTraces 读取归档中的全部轨迹
+func LoadTraces(r io.Reader) (map[string][]TraceInfo, error) {
+	ar, err := NewTraceArchiveReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer ar.Close()
+
+	data := make(map[string][]TraceInfo)
+	for {
+		no, traces, err := ar.Next()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data[no] = append(data[no], traces...)
+	}
+}
@@ -0,0 +1,125 @@
+package sto_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestTraceArchiveRoundTrip(t *testing.T) {
+	gen := stotest.NewGenerator(11)
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	data := make(map[string][]sto.TraceInfo)
+	for _, s := range stotest.Scenarios {
+		no := gen.WaybillNo()
+		data[no] = gen.Traces(no, s, start)
+	}
+	data["773000000000"] = []sto.TraceInfo{{WaybillNo: "773000000000"}}
+
+	tests := []struct {
+		name string
+		wrap func(*bytes.Buffer) (w interface{ Write([]byte) (int, error) }, done func())
+	}{
+		{"plain", func(b *bytes.Buffer) (interface{ Write([]byte) (int, error) }, func()) {
+			return b, func() {}
+		}},
+		{"gzip", func(b *bytes.Buffer) (interface{ Write([]byte) (int, error) }, func()) {
+			gz := gzip.NewWriter(b)
+			return gz, func() { gz.Close() }
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, done := tt.wrap(&buf)
+			if err := sto.SaveTraces(w, data); err != nil {
+				t.Fatal(err)
+			}
+			done()
+
+			got, err := sto.LoadTraces(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Errorf("round trip mismatch:\ngot  %v\nwant %v", got, data)
+			}
+		})
+	}
+}
+
+func TestTraceArchiveReaderCompatibility(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		want    map[string][]sto.TraceInfo
+		wantErr string
+	}{
+		{
+			name: "unknown and reordered fields",
+			archive: `{"format":"sto-trace-archive","version":1,"fields":["scanType","futureField","opTime"]}
+["773000000001",[["签收","x","2024-03-01 10:00:00"],["派件"]]]
+`,
+			want: map[string][]sto.TraceInfo{"773000000001": {
+				{WaybillNo: "773000000001", ScanType: "签收", OpTime: "2024-03-01 10:00:00"},
+				{WaybillNo: "773000000001", ScanType: "派件"},
+			}},
+		},
+		{
+			name:    "newer version",
+			archive: `{"format":"sto-trace-archive","version":99,"fields":[]}` + "\n",
+			wantErr: "unsupported trace archive version 99",
+		},
+		{
+			name:    "missing version",
+			archive: `{"format":"sto-trace-archive","fields":[]}` + "\n",
+			wantErr: "unsupported trace archive version 0",
+		},
+		{
+			name:    "negative version",
+			archive: `{"format":"sto-trace-archive","version":-1,"fields":[]}` + "\n",
+			wantErr: "unsupported trace archive version -1",
+		},
+		{
+			name:    "not an archive",
+			archive: `{"hello":"world"}` + "\n",
+			wantErr: "not a trace archive",
+		},
+		{
+			name:    "empty",
+			wantErr: "empty trace archive",
+		},
+		{
+			name: "corrupt line",
+			archive: `{"format":"sto-trace-archive","version":1,"fields":["opTime"]}
+["773000000001"]
+`,
+			wantErr: "invalid archive line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sto.LoadTraces(strings.NewReader(tt.archive))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
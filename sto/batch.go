@@ -0,0 +1,187 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+)
+
+// BatchOptions 批量操作选项
+type BatchOptions struct {
+	// ValidateOnly 只做客户端校验（必填字段、手机号、省份、直辖市的城市、运单号格式、批内重复等），
+	// 不调用申通接口，用于导入工具在提交前向用户展示可修正的问题
+	ValidateOnly bool
+
+	// Regions 设置后下单时额外校验寄/收件人的城市、区县是否属于所填省份。
+	// 未设置时不做这项校验，这类问题仍可能在提交时被申通拒绝
+	Regions *RegionTable
+}
+
+// BatchItemResult 批量操作中单项的结果
+type BatchItemResult struct {
+	Index     int               // 在输入中的位置
+	Key       string            // 订单号或运单号
+	Issues    []ValidationIssue // 客户端校验发现的问题，有问题的项不会提交
+	Submitted bool              // 是否已提交到申通
+	Err       error             // 提交失败的原因
+	WaybillNo string            // 下单成功时分配的运单号
+}
+
+// OK 该项是否校验通过且（非ValidateOnly时）提交成功
+func (r BatchItemResult) OK() bool {
+	return len(r.Issues) == 0 && r.Err == nil
+}
+
+// BatchReport 批量操作报告
+type BatchReport struct {
+	ValidateOnly bool              // 是否为只校验模式
+	Items        []BatchItemResult // 与输入一一对应
+}
+
+// OK 是否全部成功
+func (r *BatchReport) OK() bool {
+	for _, item := range r.Items {
+		if !item.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed 返回校验未通过或提交失败的项
+func (r *BatchReport) Failed() []BatchItemResult {
+	var failed []BatchItemResult
+	for _, item := range r.Items {
+		if !item.OK() {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// CreateOrders 批量下单。每个订单先做客户端校验，校验通过的订单逐个提交；
+// opts.ValidateOnly为true时只校验不提交
+func (c *Client) CreateOrders(ctx context.Context, reqs []*OrderCreateRequest, opts BatchOptions) *BatchReport {
+	report := &BatchReport{ValidateOnly: opts.ValidateOnly, Items: make([]BatchItemResult, len(reqs))}
+
+	seen := make(map[string]int)
+	for i, req := range reqs {
+		item := &report.Items[i]
+		item.Index = i
+		if req == nil {
+			item.Issues = []ValidationIssue{{Field: "order", Message: "cannot be nil"}}
+			continue
+		}
+		item.Key = req.OrderNo
+		item.Issues = req.Issues()
+		if opts.Regions != nil {
+			item.Issues = append(item.Issues, opts.Regions.Issues("sender", req.Sender)...)
+			item.Issues = append(item.Issues, opts.Regions.Issues("receiver", req.Receiver)...)
+		}
+		if first, ok := seen[req.OrderNo]; ok && req.OrderNo != "" {
+			item.Issues = append(item.Issues, ValidationIssue{
+				Field:   "orderNo",
+				Message: fmt.Sprintf("duplicate of item %d", first),
+			})
+		} else {
+			seen[req.OrderNo] = i
+		}
+	}
+	if opts.ValidateOnly {
+		return report
+	}
+
+	for i, req := range reqs {
+		item := &report.Items[i]
+		if len(item.Issues) > 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			item.Err = err
+			continue
+		}
+
+		item.Submitted = true
+		resp, err := c.CreateOrder(ctx, req)
+		if err == nil {
+			err = resp.Err()
+		}
+		if err != nil {
+			item.Err = err
+			continue
+		}
+		if resp.Data != nil {
+			item.WaybillNo = resp.Data.WaybillNo
+		}
+	}
+	return report
+}
+
+// SubscribeTraces 批量订阅轨迹推送。运单号先做格式和批内重复校验，校验通过的按批提交；
+// opts.ValidateOnly为true时只校验不提交
+func (c *Client) SubscribeTraces(ctx context.Context, waybillNos []string, opts BatchOptions) *BatchReport {
+	report := &BatchReport{ValidateOnly: opts.ValidateOnly, Items: make([]BatchItemResult, len(waybillNos))}
+
+	seen := make(map[string]int)
+	var valid []int
+	for i, no := range waybillNos {
+		item := &report.Items[i]
+		item.Index = i
+		item.Key = no
+		if !ValidWaybillNo(no) {
+			item.Issues = append(item.Issues, ValidationIssue{Field: "waybillNo", Message: fmt.Sprintf("invalid waybillNo %q", no)})
+		}
+		if first, ok := seen[no]; ok {
+			item.Issues = append(item.Issues, ValidationIssue{Field: "waybillNo", Message: fmt.Sprintf("duplicate of item %d", first)})
+		} else {
+			seen[no] = i
+		}
+		if len(item.Issues) == 0 {
+			valid = append(valid, i)
+		}
+	}
+	if opts.ValidateOnly {
+		return report
+	}
+
+	for start := 0; start < len(valid); start += traceSubscribeBatchSize {
+		end := start + traceSubscribeBatchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		c.subscribeBatch(ctx, report, waybillNos, valid[start:end])
+	}
+	return report
+}
+
+// subscribeBatch 提交一批订阅，并将结果写回报告
+func (c *Client) subscribeBatch(ctx context.Context, report *BatchReport, waybillNos []string, indexes []int) {
+	batch := make([]string, len(indexes))
+	for i, idx := range indexes {
+		batch[i] = waybillNos[idx]
+		report.Items[idx].Submitted = true
+	}
+
+	resp, err := c.SubscribeTrace(ctx, &TraceSubscribeRequest{WaybillNoList: batch})
+	if err == nil {
+		err = resp.Err()
+	}
+	if err != nil {
+		for _, idx := range indexes {
+			report.Items[idx].Err = err
+		}
+		return
+	}
+
+	// 未返回逐单结果时视为整批成功
+	failed := make(map[string]string)
+	for _, r := range resp.Data {
+		if r.Success != "true" {
+			failed[r.WaybillNo] = r.ErrorMsg
+		}
+	}
+	for _, idx := range indexes {
+		if msg, ok := failed[waybillNos[idx]]; ok {
+			report.Items[idx].Err = fmt.Errorf("subscribe failed: %s", msg)
+		}
+	}
+}
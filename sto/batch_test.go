@@ -0,0 +1,120 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestOrderContactIssues(t *testing.T) {
+	valid := testOrder("O1").Receiver
+
+	tests := []struct {
+		name   string
+		modify func(c *sto.OrderContact)
+		want   []string // 有问题的字段
+	}{
+		{"valid", func(c *sto.OrderContact) {}, nil},
+		{"province short name", func(c *sto.OrderContact) { c.Province = "浙江" }, nil},
+		{"missing name", func(c *sto.OrderContact) { c.Name = " " }, []string{"receiver.name"}},
+		{"no phone", func(c *sto.OrderContact) { c.Mobile = "" }, []string{"receiver.mobile"}},
+		{"tel only", func(c *sto.OrderContact) { c.Mobile, c.Tel = "", "0571-88888888" }, nil},
+		{"bad mobile", func(c *sto.OrderContact) { c.Mobile = "12345" }, []string{"receiver.mobile"}},
+		{"unknown province", func(c *sto.OrderContact) { c.Province = "火星" }, []string{"receiver.province"}},
+		{"missing city", func(c *sto.OrderContact) { c.City = "" }, []string{"receiver.city"}},
+		{"municipality city", func(c *sto.OrderContact) { c.Province, c.City = "上海", "上海市" }, nil},
+		{"municipality district code", func(c *sto.OrderContact) { c.Province, c.City = "北京市", "市辖区" }, nil},
+		{"municipality wrong city", func(c *sto.OrderContact) { c.Province, c.City = "上海市", "杭州市" }, []string{"receiver.city"}},
+		{"missing address", func(c *sto.OrderContact) { c.Address = "" }, []string{"receiver.address"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.modify(&c)
+			var fields []string
+			for _, issue := range c.Issues("receiver") {
+				fields = append(fields, issue.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("issues = %v, want %v", c.Issues("receiver"), tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOrdersValidateOnly(t *testing.T) {
+	calls := 0
+	gw := stotest.NewGateway(testAppSecret)
+	gw.Handle("OMS_EXPRESS_ORDER_CREATE", func(json.RawMessage) (interface{}, error) {
+		calls++
+		return map[string]string{"waybillNo": "773000000001"}, nil
+	})
+	client := newTestClient(t, gw)
+
+	bad := testOrder("O3")
+	bad.Receiver.Province, bad.Receiver.City = "重庆市", "成都市"
+	orders := []*sto.OrderCreateRequest{testOrder("O1"), testOrder("O1"), bad, nil}
+
+	tests := []struct {
+		name         string
+		opts         sto.BatchOptions
+		wantOK       []bool
+		wantCalls    int
+		wantWaybill0 string
+	}{
+		{"validate only", sto.BatchOptions{ValidateOnly: true}, []bool{true, false, false, false}, 0, ""},
+		{"submit", sto.BatchOptions{}, []bool{true, false, false, false}, 1, "773000000001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			report := client.CreateOrders(context.Background(), orders, tt.opts)
+			var ok []bool
+			for _, item := range report.Items {
+				ok = append(ok, item.OK())
+			}
+			if !reflect.DeepEqual(ok, tt.wantOK) {
+				t.Errorf("OK = %v, want %v", ok, tt.wantOK)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("gateway called %d times, want %d", calls, tt.wantCalls)
+			}
+			if report.Items[0].WaybillNo != tt.wantWaybill0 {
+				t.Errorf("WaybillNo = %q, want %q", report.Items[0].WaybillNo, tt.wantWaybill0)
+			}
+		})
+	}
+}
+
+func TestValidationErrorIsReachable(t *testing.T) {
+	client := newTestClient(t, stotest.NewGateway(testAppSecret))
+	bad := testOrder("O1")
+	bad.Receiver.Mobile = "123"
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"CreateOrder", func() error { _, err := client.CreateOrder(context.Background(), bad); return err }},
+		{"FulfillShipment", func() error { _, err := client.FulfillShipment(context.Background(), bad); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verr *sto.ValidationError
+			if err := tt.call(); !errors.As(err, &verr) {
+				t.Fatalf("err = %v, want to wrap *sto.ValidationError", err)
+			}
+			if len(verr.Issues) != 1 || verr.Issues[0].Field != "receiver.mobile" {
+				t.Errorf("issues = %v", verr.Issues)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package sto
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器打开期间请求直接失败，不会发送到网关
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// WithCircuitBreaker 开启熔断：网关连续失败threshold次（每次调用含重试算一次）后，
+// 在cooldown时间内直接返回ErrCircuitOpen；冷却结束后只放行一个试探请求，其结果返回前其余请求仍直接失败，
+// 试探成功则恢复，失败则立即重新熔断。ctx超时计为失败，调用方取消的请求不计入
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		if threshold > 0 && cooldown > 0 {
+			c.breaker = &circuitBreaker{threshold: threshold, cooldown: cooldown}
+		}
+	}
+}
+
+// circuitBreaker 按连续失败次数熔断
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool // 半开状态下的试探请求是否尚未返回
+}
+
+// allow 是否允许发送请求，probe表示该请求是冷却结束后的试探请求
+func (b *circuitBreaker) allow() (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// record 记录一次调用结果
+func (b *circuitBreaker) record(success, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// release 试探请求被调用方取消时放弃本次试探，不计入调用结果
+func (b *circuitBreaker) release(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
@@ -0,0 +1,269 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	const (
+		no       = "773000000001"
+		cooldown = 30 * time.Millisecond
+	)
+	busy := &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, ErrorMsg: "busy", NeedRetry: true}
+
+	tests := []struct {
+		name       string
+		probeFails bool
+		wantOpen   bool // 试探请求之后的下一个请求是否仍被熔断
+		wantCalls  int32
+	}{
+		{name: "probe succeeds", wantOpen: false, wantCalls: 4},
+		{name: "probe fails", probeFails: true, wantOpen: true, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			probing := make(chan struct{})
+			release := make(chan struct{})
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+				switch atomic.AddInt32(&calls, 1) {
+				case 1, 2:
+					return nil, busy
+				case 3:
+					close(probing)
+					<-release
+					if tt.probeFails {
+						return nil, busy
+					}
+				}
+				return map[string][]sto.TraceInfo{no: {{WaybillNo: no, ScanType: "收件"}}}, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0), sto.WithCircuitBreaker(2, cooldown))
+			query := func() error {
+				_, err := client.QueryTraceContext(context.Background(), &sto.TraceQueryRequest{WaybillNoList: []string{no}})
+				return err
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := query(); err == nil || errors.Is(err, sto.ErrCircuitOpen) {
+					t.Fatalf("call %d: err = %v, want gateway error", i, err)
+				}
+			}
+			if err := query(); !errors.Is(err, sto.ErrCircuitOpen) {
+				t.Fatalf("err = %v, want ErrCircuitOpen while open", err)
+			}
+
+			time.Sleep(cooldown)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				query()
+			}()
+			<-probing
+
+			// 试探请求返回前，其余请求仍然熔断
+			for i := 0; i < 3; i++ {
+				if err := query(); !errors.Is(err, sto.ErrCircuitOpen) {
+					t.Errorf("concurrent call %d: err = %v, want ErrCircuitOpen", i, err)
+				}
+			}
+			close(release)
+			wg.Wait()
+
+			err := query()
+			if got := errors.Is(err, sto.ErrCircuitOpen); got != tt.wantOpen {
+				t.Errorf("after probe: err = %v, want open %v", err, tt.wantOpen)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("gateway calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestStaleTraceFallback(t *testing.T) {
+	const (
+		cached   = "773000000001"
+		uncached = "773000000002"
+	)
+
+	tests := []struct {
+		name      string
+		query     []string
+		wantStale bool
+	}{
+		{name: "cached waybill", query: []string{cached}, wantStale: true},
+		{name: "partially cached", query: []string{cached, uncached}, wantStale: true},
+		{name: "nothing cached", query: []string{uncached}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var down atomic.Bool
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+				if down.Load() {
+					return nil, &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, NeedRetry: true}
+				}
+				return map[string][]sto.TraceInfo{cached: {{WaybillNo: cached, ScanType: "收件"}}}, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0),
+				sto.WithStaleTraceFallback(sto.NewMemoryTraceCache(time.Hour, 0)))
+
+			fresh, err := client.QueryTrace(&sto.TraceQueryRequest{WaybillNoList: []string{cached}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 修改响应不应影响缓存
+			fresh.Data[cached][0].ScanType = "篡改"
+
+			down.Store(true)
+			resp, err := client.QueryTrace(&sto.TraceQueryRequest{WaybillNoList: tt.query})
+			if !tt.wantStale {
+				if err == nil {
+					t.Fatalf("err = nil, want gateway error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Stale || resp.StaleAt.IsZero() {
+				t.Errorf("Stale = %v, StaleAt = %v, want stale response", resp.Stale, resp.StaleAt)
+			}
+			if got := resp.Data[cached][0].ScanType; got != "收件" {
+				t.Errorf("cached ScanType = %q, want 收件", got)
+			}
+			if _, ok := resp.Data[uncached]; ok {
+				t.Errorf("uncached waybill %s present in stale response", uncached)
+			}
+
+			resp.Data[cached][0].ScanType = "篡改"
+			again, err := client.QueryTrace(&sto.TraceQueryRequest{WaybillNoList: []string{cached}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := again.Data[cached][0].ScanType; got != "收件" {
+				t.Errorf("cache modified through stale response: ScanType = %q", got)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerContextErrors(t *testing.T) {
+	const no = "773000000001"
+
+	tests := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		wantOpen bool
+	}{
+		{
+			name: "deadline exceeded counts as failure",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantOpen: true,
+		},
+		{
+			name: "canceled by caller is not counted",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					time.Sleep(200 * time.Millisecond)
+				}
+				return map[string][]sto.TraceInfo{no: {{WaybillNo: no, ScanType: "收件"}}}, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0), sto.WithCircuitBreaker(1, time.Hour))
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			if _, err := client.QueryTraceContext(ctx, &sto.TraceQueryRequest{WaybillNoList: []string{no}}); err == nil {
+				t.Fatal("slow call succeeded, want context error")
+			}
+
+			_, err := client.QueryTraceContext(context.Background(), &sto.TraceQueryRequest{WaybillNoList: []string{no}})
+			if got := errors.Is(err, sto.ErrCircuitOpen); got != tt.wantOpen {
+				t.Errorf("next call: err = %v, want open %v", err, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestStaleTraceFallbackOrder(t *testing.T) {
+	const no = "773000000001"
+	asc := []sto.TraceInfo{
+		{WaybillNo: no, ScanType: "收件", OpTime: "2024-01-01 10:00:00"},
+		{WaybillNo: no, ScanType: "签收", OpTime: "2024-01-02 10:00:00"},
+	}
+
+	tests := []struct {
+		name          string
+		storeOrder    string
+		fallbackOrder string
+		wantFirst     string
+	}{
+		{"asc cached, asc fallback", "asc", "asc", "收件"},
+		{"asc cached, desc fallback", "asc", "desc", "签收"},
+		{"desc cached, desc fallback", "desc", "desc", "签收"},
+		{"desc cached, asc fallback", "desc", "asc", "收件"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var down atomic.Bool
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(content json.RawMessage) (interface{}, error) {
+				if down.Load() {
+					return nil, &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, NeedRetry: true}
+				}
+				var req sto.TraceQueryRequest
+				if err := json.Unmarshal(content, &req); err != nil {
+					return nil, err
+				}
+				traces := []sto.TraceInfo{asc[0], asc[1]}
+				if req.Order == "desc" {
+					traces[0], traces[1] = traces[1], traces[0]
+				}
+				return map[string][]sto.TraceInfo{no: traces}, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0),
+				sto.WithStaleTraceFallback(sto.NewMemoryTraceCache(time.Hour, 0)))
+
+			if _, err := client.QueryTrace(&sto.TraceQueryRequest{Order: tt.storeOrder, WaybillNoList: []string{no}}); err != nil {
+				t.Fatal(err)
+			}
+			down.Store(true)
+			resp, err := client.QueryTrace(&sto.TraceQueryRequest{Order: tt.fallbackOrder, WaybillNoList: []string{no}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Stale || len(resp.Data[no]) != 2 || resp.Data[no][0].ScanType != tt.wantFirst {
+				t.Errorf("stale traces = %+v, want %s first", resp.Data[no], tt.wantFirst)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package sto
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBulkTargetLatency 批量查询时单批的目标响应时间
+	DefaultBulkTargetLatency = 2 * time.Second
+
+	// DefaultBulkMaxBatchSize 批量查询时每批的最大运单数
+	DefaultBulkMaxBatchSize = 2 * DefaultPollBatchSize
+)
+
+// BulkTraceConfig 批量轨迹查询配置，零值字段使用默认值
+type BulkTraceConfig struct {
+	InitialBatchSize int // 初始每批运单数，默认DefaultPollBatchSize
+	MinBatchSize     int // 每批最少运单数，默认1
+	MaxBatchSize     int // 每批最多运单数，默认DefaultBulkMaxBatchSize
+
+	// TargetLatency 单批请求的目标响应时间，按单次请求计算，不含重试的退避等待。超过时缩小批次，低于一半时扩大批次
+	TargetLatency time.Duration
+	// GrowStep 网关正常时每批增加的运单数，默认2
+	GrowStep int
+}
+
+// BulkTraceResult 批量轨迹查询结果
+type BulkTraceResult struct {
+	Data   map[string][]TraceInfo // 查到的轨迹
+	Failed map[string]error       // 查询失败的运单及原因
+}
+
+// BulkTracer 批量查询大量运单的轨迹。根据每批的响应时间和错误自动调整批次大小：
+// 响应变慢、超时或网关繁忙时批次减半，超时或繁忙的批次拆小后重试；网关正常时逐步扩大批次（AIMD）。
+// 批次大小在多次Query之间保留，可并发使用
+type BulkTracer struct {
+	client *Client
+	cfg    BulkTraceConfig
+
+	mu        sync.Mutex
+	batchSize int
+}
+
+// NewBulkTracer 创建批量轨迹查询器
+func NewBulkTracer(client *Client, cfg BulkTraceConfig) *BulkTracer {
+	if cfg.MinBatchSize <= 0 {
+		cfg.MinBatchSize = 1
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = DefaultBulkMaxBatchSize
+	}
+	if cfg.MaxBatchSize < cfg.MinBatchSize {
+		cfg.MaxBatchSize = cfg.MinBatchSize
+	}
+	if cfg.InitialBatchSize <= 0 {
+		cfg.InitialBatchSize = DefaultPollBatchSize
+	}
+	if cfg.TargetLatency <= 0 {
+		cfg.TargetLatency = DefaultBulkTargetLatency
+	}
+	if cfg.GrowStep <= 0 {
+		cfg.GrowStep = 2
+	}
+
+	t := &BulkTracer{client: client, cfg: cfg}
+	t.batchSize = t.clamp(cfg.InitialBatchSize)
+	return t
+}
+
+// BatchSize 返回当前的批次大小
+func (t *BulkTracer) BatchSize() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.batchSize
+}
+
+// Query 查询全部运单的轨迹。只有ctx结束时返回错误，此时结果中包含已查到的部分
+func (t *BulkTracer) Query(ctx context.Context, waybillNos []string) (*BulkTraceResult, error) {
+	result := &BulkTraceResult{
+		Data:   make(map[string][]TraceInfo, len(waybillNos)),
+		Failed: make(map[string]error),
+	}
+
+	// next为下一批的起始位置，批次需要拆小重试时回退next即可放回队首
+	for next := 0; next < len(waybillNos); {
+		if err := ctx.Err(); err != nil {
+			for _, no := range waybillNos[next:] {
+				result.Failed[no] = err
+			}
+			return result, err
+		}
+
+		n := t.BatchSize()
+		if n > len(waybillNos)-next {
+			n = len(waybillNos) - next
+		}
+		batch := waybillNos[next : next+n]
+		next += n
+
+		resp, err := t.client.QueryTraceContext(ctx, &TraceQueryRequest{
+			Order:         "asc",
+			WaybillNoList: batch,
+		})
+		if err == nil {
+			err = resp.Err()
+		}
+
+		if err != nil && ctx.Err() == nil && isGatewayPressure(err) {
+			// 超时或网关繁忙：缩小批次，批次还能拆小时放回队首重试
+			if size := t.shrink(); len(batch) > size {
+				next -= len(batch)
+				continue
+			}
+		}
+		if err != nil {
+			for _, no := range batch {
+				result.Failed[no] = err
+			}
+			continue
+		}
+
+		for _, no := range batch {
+			result.Data[no] = resp.Data[no]
+		}
+		// 按返回数据的那次请求计算耗时，客户端重试的退避等待不计入
+		latency := resp.latency
+		switch {
+		case resp.Stale || latency > t.cfg.TargetLatency:
+			// 降级返回缓存数据也说明网关不可用
+			t.shrink()
+		case latency < t.cfg.TargetLatency/2 && n == t.BatchSize():
+			// 只有满批次的快速响应才说明可以扩大批次
+			t.grow()
+		}
+	}
+	return result, nil
+}
+
+// shrink 批次减半，返回新的批次大小
+func (t *BulkTracer) shrink() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batchSize = t.clamp(t.batchSize / 2)
+	return t.batchSize
+}
+
+// grow 批次增加GrowStep
+func (t *BulkTracer) grow() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batchSize = t.clamp(t.batchSize + t.cfg.GrowStep)
+}
+
+// clamp 将批次大小限制在配置范围内
+func (t *BulkTracer) clamp(n int) int {
+	if n < t.cfg.MinBatchSize {
+		return t.cfg.MinBatchSize
+	}
+	if n > t.cfg.MaxBatchSize {
+		return t.cfg.MaxBatchSize
+	}
+	return n
+}
@@ -0,0 +1,151 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestBulkTracer(t *testing.T) {
+	busy := &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, ErrorMsg: "busy", NeedRetry: true}
+
+	tests := []struct {
+		name       string
+		waybills   int
+		cfg        sto.BulkTraceConfig
+		maxRetries int
+		// respond 根据批次大小和第几次调用（从0开始）返回等待时间和错误
+		respond       func(size, call int) (time.Duration, error)
+		wantBatches   []int
+		wantBatchSize int
+		wantFailed    int
+	}{
+		{
+			name:     "fast responses grow batches",
+			waybills: 20,
+			cfg:      sto.BulkTraceConfig{InitialBatchSize: 2, MaxBatchSize: 6, TargetLatency: time.Second},
+			respond:  func(int, int) (time.Duration, error) { return 0, nil },
+			// 最后一批不满，不再扩大
+			wantBatches:   []int{2, 4, 6, 6, 2},
+			wantBatchSize: 6,
+		},
+		{
+			name:          "slow responses shrink batches",
+			waybills:      15,
+			cfg:           sto.BulkTraceConfig{InitialBatchSize: 8, TargetLatency: 10 * time.Millisecond},
+			respond:       func(int, int) (time.Duration, error) { return 20 * time.Millisecond, nil },
+			wantBatches:   []int{8, 4, 2, 1},
+			wantBatchSize: 1,
+		},
+		{
+			name:     "busy batches are split and retried",
+			waybills: 8,
+			cfg:      sto.BulkTraceConfig{InitialBatchSize: 8, TargetLatency: time.Second},
+			respond: func(size, _ int) (time.Duration, error) {
+				if size > 2 {
+					return 0, busy
+				}
+				return 0, nil
+			},
+			wantBatches:   []int{8, 4, 2, 4, 2, 4, 2, 2},
+			wantBatchSize: 4,
+		},
+		{
+			name:       "retry backoff is not counted as latency",
+			waybills:   4,
+			cfg:        sto.BulkTraceConfig{InitialBatchSize: 4, MaxBatchSize: 4, TargetLatency: 500 * time.Millisecond},
+			maxRetries: 1,
+			respond: func(_, call int) (time.Duration, error) {
+				if call == 0 {
+					return 0, busy
+				}
+				return 0, nil
+			},
+			wantBatches:   []int{4, 4},
+			wantBatchSize: 4,
+		},
+		{
+			name:     "rejected batch is reported",
+			waybills: 3,
+			cfg:      sto.BulkTraceConfig{InitialBatchSize: 3, TargetLatency: time.Second},
+			respond: func(int, int) (time.Duration, error) {
+				return 0, &sto.APIError{ErrorCode: sto.ErrorCodeInvalidParam, ErrorMsg: "invalid"}
+			},
+			wantBatches:   []int{3},
+			wantBatchSize: 3,
+			wantFailed:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var waybillNos []string
+			for i := 0; i < tt.waybills; i++ {
+				waybillNos = append(waybillNos, fmt.Sprintf("7730000%05d", i))
+			}
+
+			var mu sync.Mutex
+			var batches []int
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(content json.RawMessage) (interface{}, error) {
+				var req sto.TraceQueryRequest
+				if err := json.Unmarshal(content, &req); err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				call := len(batches)
+				batches = append(batches, len(req.WaybillNoList))
+				mu.Unlock()
+
+				delay, err := tt.respond(len(req.WaybillNoList), call)
+				time.Sleep(delay)
+				if err != nil {
+					return nil, err
+				}
+				data := make(map[string][]sto.TraceInfo)
+				for _, no := range req.WaybillNoList {
+					data[no] = []sto.TraceInfo{{WaybillNo: no, ScanType: "收件"}}
+				}
+				return data, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(tt.maxRetries))
+
+			tracer := sto.NewBulkTracer(client, tt.cfg)
+			result, err := tracer.Query(context.Background(), waybillNos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", batches, tt.wantBatches)
+			}
+			if got := tracer.BatchSize(); got != tt.wantBatchSize {
+				t.Errorf("BatchSize = %d, want %d", got, tt.wantBatchSize)
+			}
+			if len(result.Failed) != tt.wantFailed || len(result.Data)+len(result.Failed) != tt.waybills {
+				t.Errorf("got %d traces and %d failures, want %d failures of %d", len(result.Data), len(result.Failed), tt.wantFailed, tt.waybills)
+			}
+		})
+	}
+}
+
+func TestBulkTracerContextCancelled(t *testing.T) {
+	gw := stotest.NewGateway(testAppSecret)
+	client := newTestClient(t, gw)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := sto.NewBulkTracer(client, sto.BulkTraceConfig{}).Query(ctx, []string{"773000000001", "773000000002"})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if len(result.Failed) != 2 {
+		t.Errorf("got %d failures, want 2", len(result.Failed))
+	}
+}
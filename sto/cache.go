@@ -0,0 +1,75 @@
+package sto
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlCache 带过期时间的内存缓存，键可以是任意可比较的值，可并发使用
+type ttlCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[interface{}]cacheEntry
+}
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+// newTTLCache 创建缓存，maxEntries<=0表示不限制条目数
+func newTTLCache(ttl time.Duration, maxEntries int) *ttlCache {
+	return &ttlCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[interface{}]cacheEntry),
+	}
+}
+
+// get 读取未过期的缓存
+func (c *ttlCache) get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expireAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// set 写入缓存，超过条目上限时先清理过期条目，仍超出则淘汰最早过期的条目
+func (c *ttlCache) set(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evictLocked()
+	}
+	c.entries[key] = cacheEntry{value: value, expireAt: time.Now().Add(c.ttl)}
+}
+
+// evictLocked 清理过期条目，调用方需持有锁
+func (c *ttlCache) evictLocked() {
+	now := time.Now()
+	var oldestKey interface{}
+	var oldest time.Time
+	for k, e := range c.entries {
+		if now.After(e.expireAt) {
+			delete(c.entries, k)
+			continue
+		}
+		if oldestKey == nil || e.expireAt.Before(oldest) {
+			oldestKey, oldest = k, e.expireAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldestKey != nil {
+		delete(c.entries, oldestKey)
+	}
+}
@@ -1,13 +1,11 @@
 package sto
 
 import (
-	"crypto/md5"
-	"encoding/base64"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
-	"net/url"
+	"sort"
 	"sync"
 	"time"
 )
@@ -23,6 +21,13 @@ const (
 	DefaultMaxRetries = 3
 )
 
+// traceQueryEndpoint 轨迹查询接口
+var traceQueryEndpoint = endpoint{
+	apiName:  "STO_TRACE_QUERY_COMMON",
+	toAppKey: "sto_trace_query",
+	toCode:   "sto_trace_query",
+}
+
 // Client 申通开放平台客户端
 type Client struct {
 	AppKey    string
@@ -30,11 +35,40 @@ type Client struct {
 	FromCode  string
 	Debug     bool // 是否开启调试模式
 
-	httpClient *http.Client // HTTP客户端
-	mu         sync.RWMutex // 保护httpClient
+	httpClient  *http.Client // HTTP客户端
+	imageClient *http.Client // 下载轨迹图片使用的HTTP客户端，不含网关的域名解析和TLS设置
+	mu          sync.RWMutex // 保护httpClient
 
 	timeout    time.Duration // 超时时间
 	maxRetries int           // 最大重试次数
+
+	logger        Logger        // 日志输出
+	slowThreshold time.Duration // 慢请求阈值，0表示不记录
+
+	scanTypes *ScanTypeDictionary // 扫描类型字典
+
+	sortingCodeTTL time.Duration // 大头笔缓存时间
+	sortingCodes   *ttlCache     // 大头笔缓存
+
+	resolver    *net.Resolver       // 网关域名解析器
+	staticHosts map[string][]string // 静态域名解析表
+
+	minTLSVersion uint16   // 最低TLS版本
+	pinnedCerts   []string // 固定的网关证书指纹
+	transportErr  error    // 连接配置错误，非空时网关请求直接失败
+
+	location *time.Location // 返回时间使用的时区
+	baseURL  string         // 网关地址
+
+	usage *UsageMeter // 用量统计
+
+	amountCodec    AmountCodec    // 金额字段的编解码方式
+	errorVerbosity ErrorVerbosity // 错误信息的详细程度
+	fieldEncryptor FieldEncryptor // 字段加密器
+	fieldKeys      KeyProvider    // 字段加密密钥
+
+	breaker    *circuitBreaker // 熔断器
+	traceCache TraceCache      // 降级用的轨迹缓存
 }
 
 // ClientOption 定义客户端选项
@@ -54,6 +88,13 @@ func WithMaxRetries(maxRetries int) ClientOption {
 	}
 }
 
+// WithBaseURL 设置网关地址，默认为BaseURL，用于测试环境或本地模拟网关
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
 // WithHTTPClient 设置自定义HTTP客户端
 func WithHTTPClient(httpClient *http.Client) ClientOption {
 	return func(c *Client) {
@@ -61,6 +102,20 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// WithLogger 设置日志输出，默认输出到标准输出
+func WithLogger(logger Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithSlowRequestThreshold 设置慢请求阈值，网关调用（含重试）总耗时超过该值时记录日志
+func WithSlowRequestThreshold(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.slowThreshold = d
+	}
+}
+
 // NewClient 创建新的客户端实例
 func NewClient(appKey, appSecret, fromCode string, opts ...ClientOption) *Client {
 	c := &Client{
@@ -70,6 +125,10 @@ func NewClient(appKey, appSecret, fromCode string, opts ...ClientOption) *Client
 		Debug:      false,
 		timeout:    DefaultTimeout,
 		maxRetries: DefaultMaxRetries,
+
+		sortingCodeTTL: DefaultSortingCodeCacheTTL,
+		location:       beijingLocation,
+		baseURL:        BaseURL,
 	}
 
 	// 应用选项
@@ -77,12 +136,22 @@ func NewClient(appKey, appSecret, fromCode string, opts ...ClientOption) *Client
 		opt(c)
 	}
 
+	if c.logger == nil {
+		c.logger = stdoutLogger{}
+	}
+
+	if c.sortingCodeTTL > 0 {
+		c.sortingCodes = newTTLCache(c.sortingCodeTTL, sortingCodeCacheSize)
+	}
+
 	// 如果没有提供自定义HTTP客户端，创建默认的
 	if c.httpClient == nil {
 		c.httpClient = &http.Client{
 			Timeout: c.timeout,
 		}
 	}
+	c.imageClient = c.httpClient
+	c.configureTransport()
 
 	return c
 }
@@ -151,126 +220,90 @@ type TraceInfo struct {
 
 // TraceQueryResponse 轨迹查询响应
 type TraceQueryResponse struct {
-	Success   string                 `json:"success"`   // 是否成功
-	ErrorCode string                 `json:"errorCode"` // 错误码
-	ErrorMsg  string                 `json:"errorMsg"`  // 错误信息
-	NeedRetry string                 `json:"needRetry"` // 是否需要重试
-	RequestId string                 `json:"requestId"` // 请求ID
-	ExpInfo   string                 `json:"expInfo"`   // 异常信息
-	Data      map[string][]TraceInfo `json:"data"`      // 运单号对应的轨迹列表
-}
+	BaseResponse
+	Data map[string][]TraceInfo `json:"data"` // 运单号对应的轨迹列表
 
-// IsSuccess 检查是否成功
-func (r *TraceQueryResponse) IsSuccess() bool {
-	return r.Success == "true"
-}
+	// RecoveredWaybills 首次请求未返回轨迹、在重试中才取得轨迹的运单号
+	RecoveredWaybills []string `json:"-"`
 
-// ShouldRetry 检查是否需要重试
-func (r *TraceQueryResponse) ShouldRetry() bool {
-	return r.NeedRetry == "true"
+	// Stale 网关不可用时返回的缓存数据，见WithStaleTraceFallback
+	Stale bool `json:"-"`
+	// StaleAt 缓存数据的查询时间，多个运单时为最早的一个
+	StaleAt time.Time `json:"-"`
 }
 
 // QueryTrace 查询物流轨迹
 func (c *Client) QueryTrace(req *TraceQueryRequest) (*TraceQueryResponse, error) {
+	return c.QueryTraceContext(context.Background(), req)
+}
+
+// QueryTraceContext 查询物流轨迹，ctx用于取消请求和重试等待
+func (c *Client) QueryTraceContext(ctx context.Context, req *TraceQueryRequest) (*TraceQueryResponse, error) {
 	// 验证请求参数
 	if err := req.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid request: %v", err)
-	}
-
-	// 将请求内容转为JSON
-	content, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request failed: %v", err)
+		return nil, &InvalidRequestError{Err: err}
 	}
 
-	// 生成data_digest
-	h := md5.New()
-	h.Write([]byte(string(content) + c.AppSecret))
-	dataDigest := base64.StdEncoding.EncodeToString(h.Sum(nil))
-
-	// 构建请求参数
-	params := url.Values{}
-	params.Add("content", string(content))
-	params.Add("data_digest", dataDigest)
-	params.Add("from_appkey", c.AppKey)
-	params.Add("from_code", c.FromCode)
-	params.Add("to_appkey", "sto_trace_query")
-	params.Add("to_code", "sto_trace_query")
-	params.Add("api_name", "STO_TRACE_QUERY_COMMON")
-
-	// 构建完整URL
-	requestURL := fmt.Sprintf("%s?%s", BaseURL, params.Encode())
-
-	var resp *TraceQueryResponse
-	var lastErr error
-
-	// 重试逻辑
-	for i := 0; i <= c.maxRetries; i++ {
-		if i > 0 && c.Debug {
-			fmt.Printf("Retrying request (attempt %d/%d)\n", i, c.maxRetries)
-		}
-
-		resp, lastErr = c.doRequest(requestURL, content, dataDigest)
-		if lastErr == nil && !resp.ShouldRetry() {
-			break
-		}
-
-		if i < c.maxRetries {
-			time.Sleep(time.Duration(i+1) * time.Second) // 简单的退避策略
+	res, err := c.execute(ctx, traceQueryEndpoint, req)
+	if err != nil && ctx.Err() == nil && isGatewayPressure(err) {
+		if stale := c.staleTraces(req); stale != nil {
+			return stale, nil
 		}
 	}
-
-	return resp, lastErr
-}
-
-// doRequest 执行HTTP请求
-func (c *Client) doRequest(requestURL string, content []byte, dataDigest string) (*TraceQueryResponse, error) {
-	if c.Debug {
-		fmt.Printf("Request URL: %s\n", requestURL)
-		fmt.Printf("Content: %s\n", string(content))
-		fmt.Printf("Data Digest: %s\n", dataDigest)
+	if res == nil || len(res.attempts) == 0 {
+		return nil, err
 	}
 
-	// 创建请求
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request failed: %v", err)
+	// 最后一次尝试在传输层失败时res.resp为nil，此时以最后一次收到的响应为准，
+	// 已合并的轨迹随错误一并返回
+	last := res.resp
+	if last == nil {
+		last = res.attempts[len(res.attempts)-1]
 	}
-
-	// 设置请求头
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
-
-	// 发送请求
-	c.mu.RLock()
-	client := c.httpClient
-	debug := c.Debug
-	c.mu.RUnlock()
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
+	resp := &TraceQueryResponse{BaseResponse: last.BaseResponse}
+
+	// 合并各次尝试返回的轨迹，避免网关抖动时丢失前几次已返回的数据
+	merged := make(map[string][]TraceInfo)
+	for _, a := range res.attempts {
+		var data map[string][]TraceInfo
+		if uerr := c.decodeData(a, &data); uerr != nil {
+			if a == last {
+				return nil, uerr
+			}
+			continue
+		}
+		resp.RecoveredWaybills = append(resp.RecoveredWaybills, mergeTraceData(merged, data, a.attempt > 0)...)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response failed: %v", err)
+	if len(merged) > 0 {
+		resp.Data = merged
 	}
-
-	if debug {
-		fmt.Printf("Response Status: %d\n", resp.StatusCode)
-		fmt.Printf("Response Body: %s\n", string(body))
+	c.checkScanTypes(resp.Data)
+	if err == nil && resp.IsSuccess() {
+		c.storeTraces(req.Order, resp.Data)
 	}
 
-	// 检查状态码
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned non-200 status code: %d, body: %s", resp.StatusCode, string(body))
-	}
+	return resp, err
+}
 
-	var result TraceQueryResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("unmarshal response failed: %v, body: %s", err, string(body))
+// mergeTraceData 将一次尝试返回的轨迹合并到dst，同一运单保留轨迹条数较多的一份。
+// retry为true时返回本次新取得轨迹的运单号
+func mergeTraceData(dst, src map[string][]TraceInfo, retry bool) []string {
+	var added []string
+	for waybillNo, traces := range src {
+		if len(traces) == 0 {
+			if _, ok := dst[waybillNo]; !ok {
+				dst[waybillNo] = traces
+			}
+			continue
+		}
+		existing := dst[waybillNo]
+		if len(existing) == 0 && retry {
+			added = append(added, waybillNo)
+		}
+		if len(traces) > len(existing) {
+			dst[waybillNo] = traces
+		}
 	}
-
-	return &result, nil
+	sort.Strings(added)
+	return added
 }
@@ -0,0 +1,177 @@
+package sto_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+const (
+	testAppKey    = "test-appkey"
+	testAppSecret = "test-secret"
+	testFromCode  = "test-code"
+)
+
+// newTestClient 启动模拟网关服务器并创建指向它的客户端
+func newTestClient(t *testing.T, h http.Handler, opts ...sto.ClientOption) *sto.Client {
+	t.Helper()
+	srv := httptest.NewTLSServer(h)
+	t.Cleanup(srv.Close)
+	opts = append([]sto.ClientOption{
+		sto.WithBaseURL(srv.URL),
+		sto.WithHTTPClient(srv.Client()),
+		sto.WithLogger(log.New(io.Discard, "", 0)),
+	}, opts...)
+	return sto.NewClient(testAppKey, testAppSecret, testFromCode, opts...)
+}
+
+// sequence 按请求顺序依次使用handlers响应，超出部分重复使用最后一个
+func sequence(handlers ...http.HandlerFunc) http.Handler {
+	var n int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt64(&n, 1)) - 1
+		if i >= len(handlers) {
+			i = len(handlers) - 1
+		}
+		handlers[i](w, r)
+	})
+}
+
+// rawJSON 返回固定JSON响应
+func rawJSON(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}
+}
+
+// status 返回指定HTTP状态码
+func status(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}
+}
+
+func TestQueryTraceMergesAttempts(t *testing.T) {
+	const (
+		partial  = `{"success":"false","needRetry":"true","errorCode":"009","requestId":"r1","data":{"A":[{"waybillNo":"A","scanType":"收件"}],"B":[]}}`
+		complete = `{"success":"true","needRetry":"false","requestId":"r2","data":{"A":[{"waybillNo":"A","scanType":"收件"}],"B":[{"waybillNo":"B","scanType":"收件"},{"waybillNo":"B","scanType":"到件"}]}}`
+		busy     = `{"success":"false","needRetry":"true","errorCode":"009","requestId":"r2","data":{"B":[{"waybillNo":"B","scanType":"收件"}]}}`
+	)
+
+	tests := []struct {
+		name          string
+		handler       http.Handler
+		wantErr       bool
+		wantCounts    map[string]int
+		wantRecovered []string
+		wantRequestID string
+	}{
+		{
+			name:          "single attempt",
+			handler:       sequence(rawJSON(complete)),
+			wantCounts:    map[string]int{"A": 1, "B": 2},
+			wantRequestID: "r2",
+		},
+		{
+			name:          "retry fills missing waybill",
+			handler:       sequence(rawJSON(partial), rawJSON(complete)),
+			wantCounts:    map[string]int{"A": 1, "B": 2},
+			wantRecovered: []string{"B"},
+			wantRequestID: "r2",
+		},
+		{
+			name:          "retries exhausted keeps merged traces",
+			handler:       sequence(rawJSON(partial), rawJSON(busy)),
+			wantErr:       true,
+			wantCounts:    map[string]int{"A": 1, "B": 1},
+			wantRecovered: []string{"B"},
+			wantRequestID: "r2",
+		},
+		{
+			name:          "last attempt fails at transport level",
+			handler:       sequence(rawJSON(partial), status(http.StatusBadGateway)),
+			wantErr:       true,
+			wantCounts:    map[string]int{"A": 1, "B": 0},
+			wantRequestID: "r1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, tt.handler, sto.WithMaxRetries(1))
+			resp, err := client.QueryTraceContext(context.Background(), &sto.TraceQueryRequest{
+				Order:         "asc",
+				WaybillNoList: []string{"A", "B"},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var apiErr *sto.APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("err = %T, want *sto.APIError", err)
+				}
+			}
+			if resp == nil {
+				t.Fatal("resp = nil, want merged traces")
+			}
+			counts := make(map[string]int)
+			for no, traces := range resp.Data {
+				counts[no] = len(traces)
+			}
+			if !reflect.DeepEqual(counts, tt.wantCounts) {
+				t.Errorf("trace counts = %v, want %v", counts, tt.wantCounts)
+			}
+			if !reflect.DeepEqual(resp.RecoveredWaybills, tt.wantRecovered) {
+				t.Errorf("RecoveredWaybills = %v, want %v", resp.RecoveredWaybills, tt.wantRecovered)
+			}
+			if resp.RequestId != tt.wantRequestID {
+				t.Errorf("RequestId = %q, want %q", resp.RequestId, tt.wantRequestID)
+			}
+		})
+	}
+}
+
+func TestQueryTraceWithMockGateway(t *testing.T) {
+	gen := stotest.NewGenerator(1)
+	gw := stotest.NewGateway(testAppSecret)
+	no := gen.WaybillNo()
+	traces := gen.Traces(no, stotest.ScenarioDelivered, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	gw.SetTraces(no, traces)
+
+	client := newTestClient(t, gw)
+	resp, err := client.QueryTrace(&sto.TraceQueryRequest{Order: "asc", WaybillNoList: []string{no}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(resp.Data[no]); got != len(traces) {
+		t.Errorf("got %d traces, want %d", got, len(traces))
+	}
+}
+
+// testOrder 返回可通过校验的下单请求
+func testOrder(orderNo string) *sto.OrderCreateRequest {
+	return &sto.OrderCreateRequest{
+		OrderNo: orderNo,
+		Sender: sto.OrderContact{
+			Name: "张三", Mobile: "13800000001",
+			Province: "上海市", City: "上海市", Area: "青浦区", Address: "华新镇华志路1685号",
+		},
+		Receiver: sto.OrderContact{
+			Name: "李四", Mobile: "13900000002",
+			Province: "浙江省", City: "杭州市", Area: "西湖区", Address: "文三路90号",
+		},
+		Cargo: sto.OrderCargo{Name: "书籍", Weight: "1.2", Quantity: 1},
+	}
+}
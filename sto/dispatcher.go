@@ -0,0 +1,154 @@
+package sto
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPushWorkers 推送分发默认并发数
+	DefaultPushWorkers = 4
+
+	// DefaultPushQueueSize 推送分发默认队列长度
+	DefaultPushQueueSize = 1024
+
+	// DefaultPushRetryBackoff 推送处理失败后的默认重试间隔
+	DefaultPushRetryBackoff = time.Second
+)
+
+// PushDispatcherConfig 推送分发器配置，零值字段使用默认值
+type PushDispatcherConfig struct {
+	Workers      int           // 并发处理的worker数
+	QueueSize    int           // 缓冲队列长度，队列满时推送会应答失败由申通重推
+	MaxRetries   int           // 处理失败后的最大重试次数，0使用DefaultMaxRetries，小于0不重试
+	RetryBackoff time.Duration // 重试间隔，第n次重试等待n*RetryBackoff
+
+	// DeadLetter 重试用尽仍失败时调用，可用于落库或告警
+	DeadLetter func(event *TracePush, err error)
+}
+
+// PushDispatcher 将推送事件缓冲后由固定数量的worker分发给下游处理函数，失败时重试
+type PushDispatcher struct {
+	handle PushHandleFunc
+	cfg    PushDispatcherConfig
+
+	queue  chan *TracePush
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex // 保护closed和queue的关闭
+	closed bool
+}
+
+// NewPushDispatcher 创建推送分发器并启动worker，handle为nil时panic
+func NewPushDispatcher(handle PushHandleFunc, cfg PushDispatcherConfig) *PushDispatcher {
+	if handle == nil {
+		panic("sto: NewPushDispatcher requires a handle func")
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultPushWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultPushQueueSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultPushRetryBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &PushDispatcher{
+		handle: handle,
+		cfg:    cfg,
+		queue:  make(chan *TracePush, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	d.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Enqueue 将事件放入队列，队列已满或分发器已关闭时返回false
+func (d *PushDispatcher) Enqueue(event *TracePush) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return false
+	}
+
+	select {
+	case d.queue <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 停止接收新事件并等待队列处理完毕。ctx结束时立即返回ctx.Err()，不再等待：
+// 分发给处理函数的ctx被取消，正在等待的重试放弃并交给DeadLetter，队列中剩余的事件由worker在后台继续处理
+func (d *PushDispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		return ctx.Err()
+	}
+}
+
+// work 处理队列中的事件
+func (d *PushDispatcher) work() {
+	defer d.wg.Done()
+	for event := range d.queue {
+		d.process(event)
+	}
+}
+
+// process 处理单个事件，失败时重试，重试用尽后交给DeadLetter
+func (d *PushDispatcher) process(event *TracePush) {
+	var err error
+retry:
+	for i := 0; i <= d.cfg.MaxRetries; i++ {
+		if err = d.handle(d.ctx, event); err == nil {
+			return
+		}
+		if i == d.cfg.MaxRetries {
+			break
+		}
+
+		select {
+		case <-d.ctx.Done():
+			err = d.ctx.Err()
+			break retry
+		case <-time.After(time.Duration(i+1) * d.cfg.RetryBackoff):
+		}
+	}
+
+	if d.cfg.DeadLetter != nil {
+		d.cfg.DeadLetter(event, err)
+	}
+}
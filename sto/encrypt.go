@@ -0,0 +1,75 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// encryptTag 标记需要加密的字符串字段，如`json:"mobile" sto:"encrypt"`
+const encryptTag = "encrypt"
+
+// FieldEncryptor 使用key加密请求中标记为sto:"encrypt"的字段。
+// SDK不内置加密算法，需按申通开放平台提供的字段加密规范实现
+type FieldEncryptor interface {
+	EncryptField(key []byte, plaintext string) (string, error)
+}
+
+// FieldEncryptorFunc 将函数转换为FieldEncryptor
+type FieldEncryptorFunc func(key []byte, plaintext string) (string, error)
+
+// EncryptField 实现FieldEncryptor接口
+func (f FieldEncryptorFunc) EncryptField(key []byte, plaintext string) (string, error) {
+	return f(key, plaintext)
+}
+
+// KeyProvider 提供字段加密使用的密钥，可对接KMS等密钥管理服务以支持密钥轮换
+type KeyProvider interface {
+	Key(ctx context.Context) ([]byte, error)
+}
+
+// KeyProviderFunc 将函数转换为KeyProvider
+type KeyProviderFunc func(ctx context.Context) ([]byte, error)
+
+// Key 实现KeyProvider接口
+func (f KeyProviderFunc) Key(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// StaticKey 返回始终使用固定密钥的KeyProvider
+func StaticKey(key []byte) KeyProvider {
+	return KeyProviderFunc(func(ctx context.Context) ([]byte, error) {
+		return key, nil
+	})
+}
+
+// WithFieldEncryption 开启字段加密：调用支持加密字段的接口（如下单）时，
+// 请求中标记为sto:"encrypt"的非空字段（如收寄件人电话）在序列化时使用enc加密。
+// 每个请求只调用一次keys获取密钥，同一请求中的字段使用同一密钥
+func WithFieldEncryption(enc FieldEncryptor, keys KeyProvider) ClientOption {
+	return func(c *Client) {
+		c.fieldEncryptor = enc
+		c.fieldKeys = keys
+	}
+}
+
+// isEncryptedField 是否为标记为加密的字符串字段
+func isEncryptedField(f reflect.StructField) bool {
+	return f.Tag.Get("sto") == encryptTag && f.Type.Kind() == reflect.String
+}
+
+// encryptFields 使用key加密解码后的请求内容中指定路径上的非空字符串
+func encryptFields(v interface{}, path []string, key []byte, enc FieldEncryptor) error {
+	return walkFieldPath(v, path, func(obj map[string]interface{}, name string) error {
+		s, ok := obj[name].(string)
+		if !ok || s == "" {
+			return nil
+		}
+		encrypted, err := enc.EncryptField(key, s)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		obj[name] = encrypted
+		return nil
+	})
+}
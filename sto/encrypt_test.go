@@ -0,0 +1,138 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+// fakeEncryptor 测试用的字段加密，输出"<key>:<plaintext>"便于校验使用的密钥
+var fakeEncryptor = sto.FieldEncryptorFunc(func(key []byte, plaintext string) (string, error) {
+	return fmt.Sprintf("%s:%s", key, plaintext), nil
+})
+
+func TestFieldEncryption(t *testing.T) {
+	errKMS := errors.New("kms unavailable")
+	errCipher := errors.New("cipher failed")
+
+	tests := []struct {
+		name     string
+		encrypt  bool
+		keyErr   error
+		enc      sto.FieldEncryptor // 为nil时使用fakeEncryptor
+		wantErr  error
+		wantCode string
+	}{
+		{name: "disabled"},
+		{name: "enabled", encrypt: true},
+		{name: "key provider fails", encrypt: true, keyErr: errKMS, wantErr: errKMS, wantCode: sto.ErrorCodeUnknown},
+		{
+			name:     "key provider times out",
+			encrypt:  true,
+			keyErr:   fmt.Errorf("kms: %w", context.DeadlineExceeded),
+			wantErr:  context.DeadlineExceeded,
+			wantCode: sto.ErrorCodeTimeout,
+		},
+		{
+			name:    "encryptor fails",
+			encrypt: true,
+			enc: sto.FieldEncryptorFunc(func([]byte, string) (string, error) {
+				return "", errCipher
+			}),
+			wantErr:  errCipher,
+			wantCode: sto.ErrorCodeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content json.RawMessage
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("OMS_EXPRESS_ORDER_CREATE", func(c json.RawMessage) (interface{}, error) {
+				content = append(json.RawMessage(nil), c...)
+				return &sto.OrderCreateResult{OrderNo: "ORDER-1", WaybillNo: "773000000001"}, nil
+			})
+			var keyCalls int32
+			keys := sto.KeyProviderFunc(func(context.Context) ([]byte, error) {
+				n := atomic.AddInt32(&keyCalls, 1)
+				if tt.keyErr != nil {
+					return nil, tt.keyErr
+				}
+				return []byte(fmt.Sprintf("k%d", n)), nil
+			})
+			opts := []sto.ClientOption{sto.WithMaxRetries(0)}
+			if tt.encrypt {
+				enc := tt.enc
+				if enc == nil {
+					enc = fakeEncryptor
+				}
+				opts = append(opts, sto.WithFieldEncryption(enc, keys))
+			}
+			client := newTestClient(t, gw, opts...)
+
+			order := testOrder("ORDER-1")
+			order.Sender.IDNo = "11010119900307123X"
+			_, err := client.CreateOrder(context.Background(), order)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if got := sto.ErrorCodeOf(err); got != tt.wantCode {
+					t.Errorf("ErrorCodeOf = %q, want %q", got, tt.wantCode)
+				}
+				if content != nil {
+					t.Error("request reached the gateway after encryption failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 每个请求只获取一次密钥
+			wantCalls := int32(0)
+			if tt.encrypt {
+				wantCalls = 1
+			}
+			if got := atomic.LoadInt32(&keyCalls); got != wantCalls {
+				t.Errorf("key provider calls = %d, want %d", got, wantCalls)
+			}
+
+			var sent sto.OrderCreateRequest
+			if err := json.Unmarshal(content, &sent); err != nil {
+				t.Fatal(err)
+			}
+			fields := []struct {
+				name      string
+				got, want string
+			}{
+				{"sender.mobile", sent.Sender.Mobile, order.Sender.Mobile},
+				{"sender.idNo", sent.Sender.IDNo, order.Sender.IDNo},
+				{"receiver.mobile", sent.Receiver.Mobile, order.Receiver.Mobile},
+			}
+			for _, f := range fields {
+				want := f.want
+				if tt.encrypt {
+					want = "k1:" + f.want
+				}
+				if f.got != want {
+					t.Errorf("%s = %q, want %q", f.name, f.got, want)
+				}
+			}
+			// 未标记的字段和空值不加密
+			if sent.Sender.Name != order.Sender.Name || sent.Sender.Tel != "" || sent.Receiver.IDNo != "" {
+				t.Errorf("unexpected fields: name=%q tel=%q receiver.idNo=%q", sent.Sender.Name, sent.Sender.Tel, sent.Receiver.IDNo)
+			}
+			if tt.encrypt && strings.Contains(string(content), `"k2:`) {
+				t.Error("fields of one request encrypted with different keys")
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package sto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// 错误码在同一主版本内保持稳定：已发布的错误码不会删除，也不会改变含义，只会新增。
+// 告警规则和监控面板应使用这些错误码，而不是解析错误信息，错误信息的措辞可能随版本调整。
+
+// 网关错误码，即APIError.ErrorCode，与申通开放平台一致
+const (
+	ErrorCodeWaybillInvalid = "005" // 运单号错误
+	ErrorCodeNoPermission   = "006" // 无权限访问
+	ErrorCodeSignature      = "007" // 签名错误
+	ErrorCodeInvalidParam   = "008" // 请求参数错误
+	ErrorCodeSystemBusy     = "009" // 系统繁忙
+)
+
+// SDK错误码，由ErrorCodeOf根据SDK返回的错误生成
+const (
+	ErrorCodeValidation          = "SDK_VALIDATION"            // 请求参数未通过客户端校验，未发送到网关
+	ErrorCodeTransport           = "SDK_TRANSPORT"             // 网络错误、非200状态码或响应无法解析
+	ErrorCodeRetriesExhausted    = "SDK_RETRIES_EXHAUSTED"     // 重试次数用尽后网关仍要求重试
+	ErrorCodeCircuitOpen         = "SDK_CIRCUIT_OPEN"          // 熔断器打开，请求未发送到网关
+	ErrorCodeTimeout             = "SDK_TIMEOUT"               // ctx超时
+	ErrorCodeCanceled            = "SDK_CANCELED"              // ctx被取消
+	ErrorCodeDecode              = "SDK_DECODE"                // 响应的data字段无法解析
+	ErrorCodeNoAuthorizedAccount = "SDK_NO_AUTHORIZED_ACCOUNT" // 多账号查询时所有账号都未查到轨迹
+	ErrorCodeCompensationFailed  = "SDK_COMPENSATION_FAILED"   // Saga失败且补偿未完成，需要人工处理
+	ErrorCodeOrderNotFound       = "SDK_ORDER_NOT_FOUND"       // 按订单号查询时网关未返回订单
+	ErrorCodeUnknown             = "SDK_UNKNOWN"               // 无法归类的错误
+)
+
+// 推送应答错误码，PushHandler应答申通推送时使用
+const (
+	PushErrorCodeInvalidForm   = "S01" // 推送请求无法解析
+	PushErrorCodeInvalidDigest = "S02" // 推送签名校验失败
+	PushErrorCodeInvalidBody   = "S03" // 推送内容无法解析
+	PushErrorCodeQueueFull     = "S04" // 分发队列已满，要求申通重推
+	PushErrorCodeHandleFailed  = "S05" // 业务处理失败，要求申通重推
+)
+
+// 错误码来源
+const (
+	ErrorSourceGateway = "gateway" // 申通网关返回
+	ErrorSourceSDK     = "sdk"     // SDK生成
+	ErrorSourcePush    = "push"    // 推送应答
+)
+
+// ErrorCodeInfo 错误码说明
+type ErrorCodeInfo struct {
+	Code        string `json:"code"`        // 错误码
+	Source      string `json:"source"`      // 来源，见ErrorSource常量
+	Retryable   bool   `json:"retryable"`   // 稍后重试是否可能成功
+	Description string `json:"description"` // 说明
+	Advice      string `json:"advice"`      // 处理建议
+}
+
+// errorCatalog 全部错误码
+var errorCatalog = []ErrorCodeInfo{
+	{ErrorCodeWaybillInvalid, ErrorSourceGateway, false, "运单号错误", "检查运单号是否正确"},
+	{ErrorCodeNoPermission, ErrorSourceGateway, false, "无权限访问", "检查 APP KEY 和 APP SECRET 是否正确，以及是否有接口调用权限"},
+	{ErrorCodeSignature, ErrorSourceGateway, false, "签名错误", "检查签名生成逻辑是否正确，APP SECRET 是否正确"},
+	{ErrorCodeInvalidParam, ErrorSourceGateway, false, "请求参数错误", "检查请求参数是否完整且正确，参考接口文档"},
+	{ErrorCodeSystemBusy, ErrorSourceGateway, true, "系统繁忙", "请稍后重试，如果持续出现请联系技术支持"},
+
+	{ErrorCodeValidation, ErrorSourceSDK, false, "请求参数未通过客户端校验，未发送到网关", "根据错误信息修正请求参数"},
+	{ErrorCodeTransport, ErrorSourceSDK, true, "网络错误、非200状态码或响应无法解析", "检查网络和代理，使用 sto doctor 诊断"},
+	{ErrorCodeRetriesExhausted, ErrorSourceSDK, true, "重试次数用尽后网关仍要求重试", "稍后重试，持续出现时检查网关状态或调大 WithMaxRetries"},
+	{ErrorCodeCircuitOpen, ErrorSourceSDK, true, "熔断器打开，请求未发送到网关", "网关连续失败，等待冷却后自动恢复"},
+	{ErrorCodeTimeout, ErrorSourceSDK, true, "调用方 ctx 超时", "检查网关延迟，必要时放宽 ctx 的截止时间"},
+	{ErrorCodeCanceled, ErrorSourceSDK, false, "请求被调用方取消", "无需处理"},
+	{ErrorCodeDecode, ErrorSourceSDK, false, "响应的 data 字段无法解析", "开启 ErrorVerbosityBody 查看响应内容，并升级 SDK"},
+	{ErrorCodeNoAuthorizedAccount, ErrorSourceSDK, false, "所有账号都未查到运单轨迹", "确认运单号正确以及所属账号已加入查询"},
+	{ErrorCodeCompensationFailed, ErrorSourceSDK, false, "流程失败且补偿未完成", "根据 SagaError.CompensationErrors 人工取消订单或回收单号"},
+	{ErrorCodeOrderNotFound, ErrorSourceSDK, false, "网关未返回订单信息", "确认订单号正确且属于当前账号"},
+	{ErrorCodeUnknown, ErrorSourceSDK, false, "无法归类的错误", "查看错误信息"},
+
+	{PushErrorCodeInvalidForm, ErrorSourcePush, false, "推送请求无法解析", "确认推送地址配置正确"},
+	{PushErrorCodeInvalidDigest, ErrorSourcePush, false, "推送签名校验失败", "检查 NewPushHandler 使用的 APP SECRET"},
+	{PushErrorCodeInvalidBody, ErrorSourcePush, false, "推送内容无法解析", "记录原始推送并升级 SDK"},
+	{PushErrorCodeQueueFull, ErrorSourcePush, true, "分发队列已满，已要求申通重推", "增加 worker 数或队列长度"},
+	{PushErrorCodeHandleFailed, ErrorSourcePush, true, "业务处理失败，已要求申通重推", "查看推送处理日志"},
+}
+
+// ErrorCatalog 返回全部错误码的说明
+func ErrorCatalog() []ErrorCodeInfo {
+	return append([]ErrorCodeInfo(nil), errorCatalog...)
+}
+
+// ErrorCatalogJSON 以JSON格式返回全部错误码的说明，用于生成监控面板和告警规则
+func ErrorCatalogJSON() ([]byte, error) {
+	return json.MarshalIndent(struct {
+		Codes []ErrorCodeInfo `json:"codes"`
+	}{errorCatalog}, "", "  ")
+}
+
+// ErrorCodeOf 返回SDK错误对应的稳定错误码：网关业务错误返回网关错误码，其他错误返回SDK错误码，
+// err为nil时返回空字符串
+func ErrorCodeOf(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var sagaErr *SagaError
+	if errors.As(err, &sagaErr) && !sagaErr.FullyCompensated() {
+		return ErrorCodeCompensationFailed
+	}
+	var invalidErr *InvalidRequestError
+	var decodeErr *decodeError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &invalidErr):
+		return ErrorCodeValidation
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorCodeCircuitOpen
+	case errors.Is(err, ErrNoAuthorizedAccount):
+		return ErrorCodeNoAuthorizedAccount
+	case errors.Is(err, ErrOrderNotFound):
+		return ErrorCodeOrderNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCodeCanceled
+	case errors.As(err, &apiErr):
+		switch {
+		case apiErr.Err != nil:
+			return ErrorCodeTransport
+		case apiErr.Exhausted:
+			return ErrorCodeRetriesExhausted
+		case apiErr.ErrorCode != "":
+			return apiErr.ErrorCode
+		}
+	case errors.As(err, &decodeErr), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ErrorCodeDecode
+	}
+	return ErrorCodeUnknown
+}
@@ -0,0 +1,214 @@
+package sto_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestErrorCodesGolden 固定已发布的错误码及其是否可重试，说明和建议的措辞可以随时调整
+func TestErrorCodesGolden(t *testing.T) {
+	var got bytes.Buffer
+	seen := make(map[string]bool)
+	for _, info := range sto.ErrorCatalog() {
+		if seen[info.Code] {
+			t.Errorf("duplicate error code %s", info.Code)
+		}
+		seen[info.Code] = true
+		fmt.Fprintf(&got, "%s retryable=%v\n", info.Code, info.Retryable)
+	}
+
+	path := filepath.Join("testdata", "error_codes.golden")
+	if *update {
+		if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("%s changed; published error codes and their retryability must stay stable (run with -update only when adding codes):\ngot:\n%s", path, got.Bytes())
+	}
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	const no = "773000000001"
+	gatewayError := func(code string, retry bool) stotest.HandlerFunc {
+		return func(json.RawMessage) (interface{}, error) {
+			return nil, &sto.APIError{ErrorCode: code, ErrorMsg: "failed", NeedRetry: retry}
+		}
+	}
+	respond := func(data interface{}) stotest.HandlerFunc {
+		return func(json.RawMessage) (interface{}, error) { return data, nil }
+	}
+	slow := func(json.RawMessage) (interface{}, error) {
+		time.Sleep(200 * time.Millisecond)
+		return nil, nil
+	}
+
+	tests := []struct {
+		name    string
+		handler stotest.HandlerFunc                                 // 模拟网关的响应
+		call    func(ctx context.Context, client *sto.Client) error // 返回待分类的错误
+		timeout time.Duration
+		want    string
+	}{
+		{
+			name: "nil",
+			call: func(context.Context, *sto.Client) error { return nil },
+			want: "",
+		},
+		{
+			name: "client validation",
+			call: func(ctx context.Context, c *sto.Client) error {
+				_, err := c.QueryTraceContext(ctx, &sto.TraceQueryRequest{})
+				return err
+			},
+			want: sto.ErrorCodeValidation,
+		},
+		{
+			name: "multi-account validation",
+			call: func(ctx context.Context, c *sto.Client) error {
+				_, err := sto.QueryTraceAcrossAccounts(ctx, nil, no)
+				return err
+			},
+			want: sto.ErrorCodeValidation,
+		},
+		{
+			name:    "gateway business error",
+			handler: gatewayError(sto.ErrorCodeNoPermission, false),
+			call:    queryTraceErr(no),
+			want:    sto.ErrorCodeNoPermission,
+		},
+		{
+			name:    "gateway asks for retry",
+			handler: gatewayError(sto.ErrorCodeSystemBusy, true),
+			call:    queryTraceErr(no),
+			want:    sto.ErrorCodeSystemBusy,
+		},
+		{
+			name:    "transport timeout",
+			handler: slow,
+			call:    queryTraceErr(no),
+			timeout: 20 * time.Millisecond,
+			want:    sto.ErrorCodeTimeout,
+		},
+		{
+			name:    "undecodable data",
+			handler: respond([]string{"not", "a", "map"}),
+			call:    queryTraceErr(no),
+			want:    sto.ErrorCodeDecode,
+		},
+		{
+			name:    "undecodable amount",
+			handler: respond(map[string]string{"orderNo": "ORDER-1", "codValue": "12元"}),
+			call: func(ctx context.Context, c *sto.Client) error {
+				_, err := c.QueryOrder(ctx, &sto.OrderQueryRequest{OrderNo: "ORDER-1"})
+				return err
+			},
+			want: sto.ErrorCodeDecode,
+		},
+		{
+			name: "retries exhausted",
+			call: func(context.Context, *sto.Client) error {
+				return &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, NeedRetry: true, Exhausted: true}
+			},
+			want: sto.ErrorCodeRetriesExhausted,
+		},
+		{
+			name: "transport error",
+			call: func(context.Context, *sto.Client) error {
+				return &sto.APIError{Err: errors.New("connection reset")}
+			},
+			want: sto.ErrorCodeTransport,
+		},
+		{
+			name: "circuit open",
+			call: func(context.Context, *sto.Client) error {
+				return &sto.APIError{Err: sto.ErrCircuitOpen}
+			},
+			want: sto.ErrorCodeCircuitOpen,
+		},
+		{
+			name: "canceled",
+			call: func(context.Context, *sto.Client) error {
+				return fmt.Errorf("query: %w", context.Canceled)
+			},
+			want: sto.ErrorCodeCanceled,
+		},
+		{
+			name: "no authorized account",
+			call: func(context.Context, *sto.Client) error { return sto.ErrNoAuthorizedAccount },
+			want: sto.ErrorCodeNoAuthorizedAccount,
+		},
+		{
+			name:    "order not found",
+			handler: respond(nil),
+			call: func(ctx context.Context, c *sto.Client) error {
+				_, err := c.GetShipmentStatusByOrderNo(ctx, "ORDER-1")
+				return err
+			},
+			want: sto.ErrorCodeOrderNotFound,
+		},
+		{
+			name: "compensation failed",
+			call: func(context.Context, *sto.Client) error {
+				return &sto.SagaError{Step: "b", CompensationErrors: []sto.StepError{{Step: "a", Err: errors.New("x")}}}
+			},
+			want: sto.ErrorCodeCompensationFailed,
+		},
+		{
+			name: "unknown",
+			call: func(context.Context, *sto.Client) error { return errors.New("boom") },
+			want: sto.ErrorCodeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := stotest.NewGateway(testAppSecret)
+			if tt.handler != nil {
+				gw.Handle("STO_TRACE_QUERY_COMMON", tt.handler)
+				gw.Handle("OMS_EXPRESS_ORDER_QUERY", tt.handler)
+			}
+			client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			err := tt.call(ctx, client)
+			if got := sto.ErrorCodeOf(err); got != tt.want {
+				t.Errorf("ErrorCodeOf(%v) = %q, want %q", err, got, tt.want)
+			}
+		})
+	}
+}
+
+// queryTraceErr 返回查询单个运单轨迹的错误，网关返回失败时为resp.Err()
+func queryTraceErr(no string) func(ctx context.Context, c *sto.Client) error {
+	return func(ctx context.Context, c *sto.Client) error {
+		resp, err := c.QueryTraceContext(ctx, &sto.TraceQueryRequest{WaybillNoList: []string{no}})
+		if err == nil {
+			err = resp.Err()
+		}
+		return err
+	}
+}
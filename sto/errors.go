@@ -0,0 +1,111 @@
+package sto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIError 网关调用失败时返回的错误，包含网关返回的错误信息及重试情况
+type APIError struct {
+	APIName   string        // 接口名称
+	ErrorCode string        // 网关返回的错误码
+	ErrorMsg  string        // 网关返回的错误信息
+	NeedRetry bool          // 网关是否建议重试
+	RequestId string        // 请求ID
+	Attempts  int           // 实际请求次数
+	Elapsed   time.Duration // 总耗时，含重试等待时间
+	Exhausted bool          // 是否因重试次数用尽而放弃
+	Err       error         // 底层错误（网络错误、非200状态码等），网关返回业务错误时为nil
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sto api %s failed", e.APIName)
+	if e.ErrorCode != "" || e.ErrorMsg != "" {
+		fmt.Fprintf(&b, ": %s - %s", e.ErrorCode, e.ErrorMsg)
+	}
+	if e.RequestId != "" {
+		fmt.Fprintf(&b, ", requestId: %s", e.RequestId)
+	}
+	fmt.Fprintf(&b, ", attempts: %d, elapsed: %s", e.Attempts, e.Elapsed)
+	if e.Exhausted {
+		b.WriteString(", retries exhausted")
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+// Unwrap 返回底层错误
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// isGatewayPressure 判断错误是否说明网关异常或限流（而不是请求本身的问题），轮询退避和单号回收据此决定是否重试
+func isGatewayPressure(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Err != nil || apiErr.NeedRetry || apiErr.ErrorCode == ErrorCodeSystemBusy
+}
+
+// InvalidRequestError 请求参数未通过客户端校验，请求没有发送到网关
+type InvalidRequestError struct {
+	Err error // 校验错误，下单等接口为*ValidationError
+}
+
+// Error 实现error接口
+func (e *InvalidRequestError) Error() string {
+	return fmt.Sprintf("invalid request: %v", e.Err)
+}
+
+// Unwrap 返回校验错误
+func (e *InvalidRequestError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError 网关调用成功但响应的data字段无法解析，ErrorCodeOf据此返回ErrorCodeDecode
+type decodeError struct {
+	err error
+}
+
+// Error 实现error接口
+func (e *decodeError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回解析错误
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// ErrorVerbosity 错误信息的详细程度
+type ErrorVerbosity int
+
+const (
+	// ErrorVerbosityCodes 错误信息只包含HTTP状态码、错误码和requestId，不包含响应内容（默认）。
+	// 响应中可能含有收寄件人姓名、电话、地址等个人信息，生产环境应使用该级别
+	ErrorVerbosityCodes ErrorVerbosity = iota
+	// ErrorVerbosityBody 错误信息包含完整的响应内容，便于开发调试
+	ErrorVerbosityBody
+)
+
+// WithErrorVerbosity 设置网关响应异常（非200状态码、无法解析等）时错误信息的详细程度，默认ErrorVerbosityCodes
+func WithErrorVerbosity(v ErrorVerbosity) ClientOption {
+	return func(c *Client) {
+		c.errorVerbosity = v
+	}
+}
+
+// bodyDetail 按错误详细程度返回附加到错误信息中的响应内容
+func (c *Client) bodyDetail(body []byte) string {
+	if c.errorVerbosity >= ErrorVerbosityBody {
+		return fmt.Sprintf(", body: %s", body)
+	}
+	return fmt.Sprintf(", body length: %d", len(body))
+}
@@ -0,0 +1,201 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestAPIErrorRetryDetails(t *testing.T) {
+	const no = "773000000001"
+	busy := &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, ErrorMsg: "busy", NeedRetry: true}
+	invalid := &sto.APIError{ErrorCode: sto.ErrorCodeInvalidParam, ErrorMsg: "invalid"}
+
+	tests := []struct {
+		name        string
+		responses   []error // 网关依次返回的错误，超出部分重复使用最后一个
+		transport   bool    // 以500状态码代替网关业务错误
+		cancelAfter time.Duration
+		wantCode    string // ErrorCodeOf的结果
+		wantGateway string // APIError.ErrorCode，即最后一次响应的网关错误码
+		wantAttempt int
+		wantExhaust bool
+		wantElapsed time.Duration // 最少耗时，含重试等待
+	}{
+		{
+			name:        "gateway keeps asking for retry",
+			responses:   []error{busy},
+			wantCode:    sto.ErrorCodeRetriesExhausted,
+			wantGateway: sto.ErrorCodeSystemBusy,
+			wantAttempt: 2,
+			wantExhaust: true,
+			wantElapsed: time.Second,
+		},
+		{
+			name:        "business error after a retry",
+			responses:   []error{busy, invalid},
+			wantCode:    sto.ErrorCodeInvalidParam,
+			wantGateway: sto.ErrorCodeInvalidParam,
+			wantAttempt: 2,
+			wantElapsed: time.Second,
+		},
+		{
+			name:        "transport error is retried",
+			transport:   true,
+			wantCode:    sto.ErrorCodeTransport,
+			wantAttempt: 2,
+			wantExhaust: true,
+			wantElapsed: time.Second,
+		},
+		{
+			name:        "canceled while waiting to retry",
+			responses:   []error{busy},
+			cancelAfter: 100 * time.Millisecond,
+			wantCode:    sto.ErrorCodeCanceled,
+			wantGateway: sto.ErrorCodeSystemBusy,
+			wantAttempt: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int64
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+				i := int(atomic.AddInt64(&calls, 1)) - 1
+				if i >= len(tt.responses) {
+					i = len(tt.responses) - 1
+				}
+				return nil, tt.responses[i]
+			})
+			var h http.Handler = gw
+			if tt.transport {
+				h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt64(&calls, 1)
+					w.WriteHeader(http.StatusInternalServerError)
+				})
+			}
+			client := newTestClient(t, h, sto.WithMaxRetries(1))
+
+			ctx := context.Background()
+			if tt.cancelAfter > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				time.AfterFunc(tt.cancelAfter, cancel)
+				defer cancel()
+			}
+
+			resp, err := client.QueryTraceContext(ctx, &sto.TraceQueryRequest{WaybillNoList: []string{no}})
+			if err == nil {
+				err = resp.Err()
+			}
+			var apiErr *sto.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *sto.APIError", err)
+			}
+			if got := sto.ErrorCodeOf(err); got != tt.wantCode {
+				t.Errorf("ErrorCodeOf = %q, want %q", got, tt.wantCode)
+			}
+			if apiErr.ErrorCode != tt.wantGateway {
+				t.Errorf("ErrorCode = %q, want %q", apiErr.ErrorCode, tt.wantGateway)
+			}
+			if apiErr.APIName != "STO_TRACE_QUERY_COMMON" {
+				t.Errorf("APIName = %q", apiErr.APIName)
+			}
+			if apiErr.Attempts != tt.wantAttempt || int(atomic.LoadInt64(&calls)) != tt.wantAttempt {
+				t.Errorf("Attempts = %d, gateway calls = %d, want %d", apiErr.Attempts, calls, tt.wantAttempt)
+			}
+			if apiErr.Exhausted != tt.wantExhaust {
+				t.Errorf("Exhausted = %v, want %v", apiErr.Exhausted, tt.wantExhaust)
+			}
+			if apiErr.Elapsed < tt.wantElapsed || apiErr.Elapsed > tt.wantElapsed+time.Second {
+				t.Errorf("Elapsed = %s, want about %s", apiErr.Elapsed, tt.wantElapsed)
+			}
+		})
+	}
+}
+
+func TestAPIErrorNoRetryIsNotExhausted(t *testing.T) {
+	gw := stotest.NewGateway(testAppSecret)
+	gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+		return nil, &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, NeedRetry: true}
+	})
+	client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+	_, err := client.QueryTraceContext(context.Background(), &sto.TraceQueryRequest{WaybillNoList: []string{"773000000001"}})
+	var apiErr *sto.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *sto.APIError", err)
+	}
+	if apiErr.Attempts != 1 || apiErr.Exhausted || !apiErr.NeedRetry {
+		t.Errorf("APIError = %+v, want one attempt, not exhausted, gateway asking for retry", apiErr)
+	}
+}
+
+func TestWithErrorVerbosity(t *testing.T) {
+	// 响应内容中可能含有个人信息，默认不应出现在错误信息中
+	const phone = "13800000000"
+	badGateway := `<html>receiver ` + phone + `</html>`
+	garbled := `{"success":"true","receiver":"` + phone + `"`
+	data := `"receiver ` + phone + `"`
+	undecodable := `{"success":"true","requestId":"r1","data":` + data + `}`
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string // 错误信息中附带的内容，data无法解析时只附带data字段
+	}{
+		{name: "non-200 status", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, badGateway)
+		}, body: badGateway},
+		{name: "unparseable response", handler: rawJSON(garbled), body: garbled},
+		{name: "undecodable data", handler: rawJSON(undecodable), body: data},
+	}
+
+	for _, tt := range tests {
+		// -1表示不设置WithErrorVerbosity，使用默认值
+		for _, v := range []sto.ErrorVerbosity{-1, sto.ErrorVerbosityCodes, sto.ErrorVerbosityBody} {
+			name := tt.name + "/default"
+			var opts []sto.ClientOption
+			if v >= 0 {
+				name = fmt.Sprintf("%s/verbosity %d", tt.name, v)
+				opts = append(opts, sto.WithErrorVerbosity(v))
+			}
+			t.Run(name, func(t *testing.T) {
+				client := newTestClient(t, tt.handler, append(opts, sto.WithMaxRetries(0))...)
+
+				_, err := client.QueryTraceContext(context.Background(), &sto.TraceQueryRequest{WaybillNoList: []string{"773000000001"}})
+				if err == nil {
+					t.Fatal("err = nil, want gateway error")
+				}
+				msg := err.Error()
+				if v == sto.ErrorVerbosityBody {
+					if !strings.Contains(msg, "body: "+tt.body) {
+						t.Errorf("err = %q, want response body included", msg)
+					}
+					return
+				}
+				if strings.Contains(msg, phone) {
+					t.Errorf("err = %q, want response body left out", msg)
+				}
+				if want := fmt.Sprintf("body length: %d", len(tt.body)); !strings.Contains(msg, want) {
+					t.Errorf("err = %q, want %q", msg, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// etaArrivedWindow 到达目的城市后预计送达的时间窗口
+	etaArrivedWindow = 24 * time.Hour
+
+	// etaDeliveryWindow 派件后预计送达的时间窗口
+	etaDeliveryWindow = 8 * time.Hour
+)
+
+// etaQueryEndpoint 运费时效查询接口
+var etaQueryEndpoint = endpoint{
+	apiName:  "STO_TIME_EFFECT_QUERY",
+	toAppKey: "sto_time_effect",
+	toCode:   "sto_time_effect",
+
+	emptyFields: emptyFieldsOmit,
+}
+
+// ETAQueryRequest 运费时效查询请求参数
+type ETAQueryRequest struct {
+	SenderProvince   string `json:"senderProvince"`   // 寄件省
+	SenderCity       string `json:"senderCity"`       // 寄件市
+	SenderArea       string `json:"senderArea"`       // 寄件区县
+	ReceiverProvince string `json:"receiverProvince"` // 收件省
+	ReceiverCity     string `json:"receiverCity"`     // 收件市
+	ReceiverArea     string `json:"receiverArea"`     // 收件区县
+	Weight           string `json:"weight"`           // 重量，单位：kg
+	SendTime         string `json:"sendTime"`         // 寄件时间，北京时间，格式：yyyy-MM-dd HH:mm:ss，可用FormatTime生成，为空表示当前时间
+}
+
+// Validate 验证请求参数
+func (r *ETAQueryRequest) Validate() error {
+	if r.SenderProvince == "" || r.SenderCity == "" {
+		return fmt.Errorf("senderProvince and senderCity cannot be empty")
+	}
+	if r.ReceiverProvince == "" || r.ReceiverCity == "" {
+		return fmt.Errorf("receiverProvince and receiverCity cannot be empty")
+	}
+	return nil
+}
+
+// ETAInfo 运费时效信息
+type ETAInfo struct {
+	EstimatedHours     int    `json:"estimatedHours"`     // 预计运输时长，单位：小时
+	EarliestArriveTime string `json:"earliestArriveTime"` // 最早送达时间
+	LatestArriveTime   string `json:"latestArriveTime"`   // 最晚送达时间
+	Freight            Amount `json:"freight"`            // 预估运费，单位：元
+}
+
+// ETAQueryResponse 运费时效查询响应
+type ETAQueryResponse struct {
+	BaseResponse
+	Data *ETAInfo `json:"data"` // 运费时效信息
+}
+
+// QueryETA 查询线路的预计送达时间和运费
+func (c *Client) QueryETA(ctx context.Context, req *ETAQueryRequest) (*ETAQueryResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, etaQueryEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &ETAQueryResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
+
+// ETA 预计送达时间窗口的依据
+const (
+	ETABasisInitial         = "initial"          // 运费时效接口的初始预估
+	ETABasisInTransit       = "in_transit"       // 运输延误，按最新轨迹顺延
+	ETABasisDestinationCity = "destination_city" // 已到达目的城市
+	ETABasisOutForDelivery  = "out_for_delivery" // 派送中
+	ETABasisDelivered       = "delivered"        // 已签收
+	ETABasisReturned        = "returned"         // 退回寄件人，不会送达收件人，送达时间为零值
+)
+
+// ETAEstimate 预计送达时间窗口
+type ETAEstimate struct {
+	WaybillNo string    // 运单号
+	Earliest  time.Time // 最早送达时间
+	Latest    time.Time // 最晚送达时间
+	Milestone Milestone // 当前标准节点
+	Basis     string    // 预估依据，见ETABasis常量
+	LastScan  time.Time // 参与修正的最新一条轨迹的时间
+}
+
+// RollingETA 结合运费时效接口的初始预估和实时轨迹，返回修正后的预计送达时间。
+// route.SendTime为空时使用运单第一条轨迹的时间作为寄件时间
+func (c *Client) RollingETA(ctx context.Context, waybillNo string, route *ETAQueryRequest) (*ETAEstimate, error) {
+	if route == nil {
+		return nil, &InvalidRequestError{Err: fmt.Errorf("route cannot be nil")}
+	}
+
+	traceResp, err := c.QueryTraceContext(ctx, &TraceQueryRequest{
+		Order:         "asc",
+		WaybillNoList: []string{waybillNo},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := traceResp.Err(); err != nil {
+		return nil, err
+	}
+	traces := traceResp.Data[waybillNo]
+
+	etaReq := *route
+	if etaReq.SendTime == "" && len(traces) > 0 {
+		etaReq.SendTime = traces[0].OpTime
+	}
+	etaResp, err := c.QueryETA(ctx, &etaReq)
+	if err != nil {
+		return nil, err
+	}
+	if err := etaResp.Err(); err != nil {
+		return nil, err
+	}
+	if etaResp.Data == nil {
+		return nil, fmt.Errorf("eta not available for waybill %s", waybillNo)
+	}
+
+	initial, err := initialETA(etaResp.Data, etaReq.SendTime)
+	if err != nil {
+		return nil, err
+	}
+	initial.WaybillNo = waybillNo
+
+	revised := ReviseETA(*initial, traces, route.ReceiverCity)
+	if revised.Basis != ETABasisReturned {
+		revised.Earliest = revised.Earliest.In(c.location)
+		revised.Latest = revised.Latest.In(c.location)
+	}
+	if !revised.LastScan.IsZero() {
+		revised.LastScan = revised.LastScan.In(c.location)
+	}
+	return &revised, nil
+}
+
+// initialETA 根据运费时效信息生成初始预估
+func initialETA(info *ETAInfo, sendTime string) (*ETAEstimate, error) {
+	eta := &ETAEstimate{Milestone: MilestoneInfoReceived, Basis: ETABasisInitial}
+
+	if info.EarliestArriveTime != "" && info.LatestArriveTime != "" {
+		var err error
+		if eta.Earliest, err = ParseTime(info.EarliestArriveTime); err != nil {
+			return nil, fmt.Errorf("invalid earliestArriveTime: %v", err)
+		}
+		if eta.Latest, err = ParseTime(info.LatestArriveTime); err != nil {
+			return nil, fmt.Errorf("invalid latestArriveTime: %v", err)
+		}
+		return eta, nil
+	}
+
+	if info.EstimatedHours <= 0 {
+		return nil, fmt.Errorf("eta response has neither arrive time nor estimatedHours")
+	}
+	start := time.Now().In(beijingLocation)
+	if sendTime != "" {
+		t, err := ParseTime(sendTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sendTime: %v", err)
+		}
+		start = t
+	}
+	eta.Earliest = start.Add(time.Duration(info.EstimatedHours) * time.Hour)
+	eta.Latest = eta.Earliest.Add(etaArrivedWindow)
+	return eta, nil
+}
+
+// ReviseETA 根据轨迹修正预计送达时间：签收或放入快递柜后为该轨迹时间；派件后为当天内；
+// 到达目的城市后为一天内；运输延误时按最新轨迹顺延；退回中或已退回时不会送达收件人，
+// 送达时间为零值。destCity为收件城市
+func ReviseETA(eta ETAEstimate, traces []TraceInfo, destCity string) ETAEstimate {
+	latest := latestTrace(traces)
+	if latest == nil {
+		return eta
+	}
+	scanAt, err := ParseTime(latest.OpTime)
+	if err != nil {
+		return eta
+	}
+
+	eta.Milestone = CurrentMilestone(traces)
+	eta.LastScan = scanAt
+
+	switch {
+	case eta.Milestone == MilestoneReturning || eta.Milestone == MilestoneReturned:
+		eta.Earliest, eta.Latest = time.Time{}, time.Time{}
+		eta.Basis = ETABasisReturned
+	case eta.Milestone == MilestoneDelivered || eta.Milestone == MilestoneAwaitingPickup:
+		// 放入快递柜即送达收件地址，取件时间取决于收件人
+		eta.Earliest, eta.Latest = scanAt, scanAt
+		eta.Basis = ETABasisDelivered
+	case eta.Milestone == MilestoneOutForDelivery:
+		eta.Earliest, eta.Latest = scanAt, scanAt.Add(etaDeliveryWindow)
+		eta.Basis = ETABasisOutForDelivery
+	case arrivedAtCity(traces, destCity):
+		eta.Earliest, eta.Latest = scanAt, scanAt.Add(etaArrivedWindow)
+		eta.Basis = ETABasisDestinationCity
+	case scanAt.After(eta.Earliest):
+		// 预计最早送达时间已过但仍在运输中，按最新轨迹顺延
+		eta.Earliest = scanAt
+		if eta.Latest.Before(scanAt.Add(etaArrivedWindow)) {
+			eta.Latest = scanAt.Add(etaArrivedWindow)
+		}
+		eta.Basis = ETABasisInTransit
+	}
+	return eta
+}
+
+// arrivedAtCity 判断运单是否已有到达目的城市的轨迹
+func arrivedAtCity(traces []TraceInfo, city string) bool {
+	city = normalizeCity(city)
+	if city == "" {
+		return false
+	}
+	for _, t := range traces {
+		if t.ScanType == "到件" && normalizeCity(t.OpOrgCityName) == city {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCity 去掉城市名称末尾的"市"，便于比较
+func normalizeCity(city string) string {
+	return strings.TrimSuffix(strings.TrimSpace(city), "市")
+}
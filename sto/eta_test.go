@@ -0,0 +1,130 @@
+package sto_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestReviseETA(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := sto.ParseTime(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	trace := func(opTime, scanType, city string) sto.TraceInfo {
+		return sto.TraceInfo{OpTime: opTime, ScanType: scanType, OpOrgCityName: city}
+	}
+	initial := sto.ETAEstimate{
+		Earliest:  at("2024-03-03 09:00:00"),
+		Latest:    at("2024-03-04 18:00:00"),
+		Milestone: sto.MilestoneInfoReceived,
+		Basis:     sto.ETABasisInitial,
+	}
+
+	tests := []struct {
+		name         string
+		traces       []sto.TraceInfo
+		wantBasis    string
+		wantEarliest time.Time
+		wantLatest   time.Time
+	}{
+		{
+			name:         "no traces",
+			wantBasis:    sto.ETABasisInitial,
+			wantEarliest: initial.Earliest,
+			wantLatest:   initial.Latest,
+		},
+		{
+			name:         "on schedule",
+			traces:       []sto.TraceInfo{trace("2024-03-01 10:00:00", "收件", "上海市")},
+			wantBasis:    sto.ETABasisInitial,
+			wantEarliest: initial.Earliest,
+			wantLatest:   initial.Latest,
+		},
+		{
+			name: "delayed in transit",
+			traces: []sto.TraceInfo{
+				trace("2024-03-01 10:00:00", "收件", "上海市"),
+				trace("2024-03-04 12:00:00", "发件", "上海市"),
+			},
+			wantBasis:    sto.ETABasisInTransit,
+			wantEarliest: at("2024-03-04 12:00:00"),
+			wantLatest:   at("2024-03-05 12:00:00"),
+		},
+		{
+			name: "arrived at destination city",
+			traces: []sto.TraceInfo{
+				trace("2024-03-01 10:00:00", "收件", "上海市"),
+				trace("2024-03-02 06:00:00", "到件", "杭州"),
+			},
+			wantBasis:    sto.ETABasisDestinationCity,
+			wantEarliest: at("2024-03-02 06:00:00"),
+			wantLatest:   at("2024-03-03 06:00:00"),
+		},
+		{
+			name: "out for delivery",
+			traces: []sto.TraceInfo{
+				trace("2024-03-02 06:00:00", "到件", "杭州市"),
+				trace("2024-03-02 08:00:00", "派件", "杭州市"),
+			},
+			wantBasis:    sto.ETABasisOutForDelivery,
+			wantEarliest: at("2024-03-02 08:00:00"),
+			wantLatest:   at("2024-03-02 16:00:00"),
+		},
+		{
+			name: "delivered",
+			traces: []sto.TraceInfo{
+				trace("2024-03-02 08:00:00", "派件", "杭州市"),
+				trace("2024-03-02 11:00:00", "签收", "杭州市"),
+			},
+			wantBasis:    sto.ETABasisDelivered,
+			wantEarliest: at("2024-03-02 11:00:00"),
+			wantLatest:   at("2024-03-02 11:00:00"),
+		},
+		{
+			name: "being returned",
+			traces: []sto.TraceInfo{
+				trace("2024-03-02 08:00:00", "派件", "杭州市"),
+				trace("2024-03-02 11:00:00", "退回件", "杭州市"),
+				trace("2024-03-02 20:00:00", "到件", "上海市"),
+			},
+			wantBasis: sto.ETABasisReturned,
+		},
+		{
+			name: "returned and signed",
+			traces: []sto.TraceInfo{
+				trace("2024-03-02 11:00:00", "退回件", "杭州市"),
+				trace("2024-03-03 15:00:00", "签收", "上海市"),
+			},
+			wantBasis: sto.ETABasisReturned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sto.ReviseETA(initial, tt.traces, "杭州市")
+			if got.Basis != tt.wantBasis {
+				t.Errorf("Basis = %s, want %s", got.Basis, tt.wantBasis)
+			}
+			if !got.Earliest.Equal(tt.wantEarliest) || !got.Latest.Equal(tt.wantLatest) {
+				t.Errorf("window = %v ~ %v, want %v ~ %v", got.Earliest, got.Latest, tt.wantEarliest, tt.wantLatest)
+			}
+		})
+	}
+}
+
+func TestRollingETANilRoute(t *testing.T) {
+	client := newTestClient(t, stotest.NewGateway(testAppSecret))
+	_, err := client.RollingETA(context.Background(), "773000000001", nil)
+	var invalid *sto.InvalidRequestError
+	if !errors.As(err, &invalid) {
+		t.Errorf("err = %v, want *sto.InvalidRequestError", err)
+	}
+}
@@ -0,0 +1,136 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+)
+
+// 发货流程的步骤名称
+const (
+	FulfillStepCreateOrder = "create_order"    // 下单
+	FulfillStepWaybillNo   = "obtain_waybill"  // 获取运单号
+	FulfillStepSubscribe   = "subscribe_trace" // 订阅轨迹推送
+)
+
+// fulfillCancelReason 补偿时取消订单、回收单号的原因
+const fulfillCancelReason = "fulfillment rolled back"
+
+// FulfillmentResult 发货流程的结果。流程失败时仍包含已取得的订单号和运单号，便于核对补偿结果
+type FulfillmentResult struct {
+	OrderNo    string // 商家订单号
+	WaybillNo  string // 运单号
+	BigChar    string // 大头笔
+	Subscribed bool   // 是否已订阅轨迹推送
+}
+
+// FulfillShipment 完成一次发货：下单、获取运单号、订阅轨迹推送。
+// 中途失败时按相反顺序补偿：回收通过订单查询取得的运单号、取消订单，并返回*SagaError，
+// 其中CompensationErrors非空表示补偿未完成，需要人工处理。
+// 下单因网络错误、超时或响应无法解析而失败时，订单可能已经创建，同样尝试取消订单
+func (c *Client) FulfillShipment(ctx context.Context, order *OrderCreateRequest) (*FulfillmentResult, error) {
+	if err := order.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	result := &FulfillmentResult{OrderNo: order.OrderNo}
+	queried := false // 运单号是否由获取运单号步骤取得，下单接口直接返回的运单号随取消订单一并作废
+	saga := &Saga{}
+	saga.Step(FulfillStepCreateOrder, func(ctx context.Context) error {
+		resp, err := c.CreateOrder(ctx, order)
+		if err != nil && outcomeUnknown(err) {
+			return fmt.Errorf("%w: %w", ErrOutcomeUnknown, err)
+		}
+		if err == nil {
+			err = resp.Err()
+		}
+		if err != nil {
+			return err
+		}
+		if resp.Data != nil {
+			result.WaybillNo = resp.Data.WaybillNo
+			result.BigChar = resp.Data.BigChar
+		}
+		return nil
+	}, func(ctx context.Context) error {
+		resp, err := c.CancelOrder(ctx, &OrderCancelRequest{
+			OrderNo:   result.OrderNo,
+			WaybillNo: result.WaybillNo,
+			Reason:    fulfillCancelReason,
+		})
+		if err == nil {
+			err = resp.Err()
+		}
+		return err
+	})
+
+	saga.Step(FulfillStepWaybillNo, func(ctx context.Context) error {
+		if result.WaybillNo != "" {
+			return nil
+		}
+		// 下单接口未直接返回运单号时，通过订单查询获取
+		resp, err := c.QueryOrder(ctx, &OrderQueryRequest{OrderNo: result.OrderNo})
+		if err == nil {
+			err = resp.Err()
+		}
+		if err != nil {
+			return err
+		}
+		if resp.Data == nil || resp.Data.WaybillNo == "" {
+			return fmt.Errorf("no waybillNo assigned for order %s", result.OrderNo)
+		}
+		result.WaybillNo = resp.Data.WaybillNo
+		queried = true
+		return nil
+	}, func(ctx context.Context) error {
+		if !queried {
+			return ErrNothingToCompensate
+		}
+		resp, err := c.ReturnWaybillNos(ctx, &WaybillReturnRequest{
+			WaybillNoList: []string{result.WaybillNo},
+			Reason:        fulfillCancelReason,
+		})
+		if err == nil {
+			err = resp.Err()
+		}
+		if err != nil {
+			return err
+		}
+		for _, r := range resp.Data {
+			if r.Success != "true" {
+				return fmt.Errorf("return waybillNo %s failed: %s", r.WaybillNo, r.ErrorMsg)
+			}
+		}
+		return nil
+	})
+
+	saga.Step(FulfillStepSubscribe, func(ctx context.Context) error {
+		resp, err := c.SubscribeTrace(ctx, &TraceSubscribeRequest{WaybillNoList: []string{result.WaybillNo}})
+		if err == nil {
+			err = resp.Err()
+		}
+		if err != nil {
+			return err
+		}
+		for _, r := range resp.Data {
+			if r.Success != "true" {
+				return fmt.Errorf("subscribe waybillNo %s failed: %s", r.WaybillNo, r.ErrorMsg)
+			}
+		}
+		result.Subscribed = true
+		return nil
+	}, nil)
+
+	if err := saga.Run(ctx); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// outcomeUnknown 调用失败时无法确定请求是否已被网关处理：网络错误、超时、取消或响应无法解析
+func outcomeUnknown(err error) bool {
+	switch ErrorCodeOf(err) {
+	case ErrorCodeTransport, ErrorCodeTimeout, ErrorCodeCanceled, ErrorCodeDecode:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestFulfillShipmentCompensation(t *testing.T) {
+	const (
+		orderNo   = "ORDER-1"
+		waybillNo = "773000000001"
+	)
+	rejected := &sto.APIError{ErrorCode: "S01", ErrorMsg: "rejected"}
+
+	tests := []struct {
+		name            string
+		createReturnsNo bool            // 下单接口是否直接返回运单号
+		createGarbled   bool            // 下单接口返回无法解析的data，无法确定订单是否已创建
+		fail            map[string]bool // 返回失败的接口
+		wantStep        string          // 失败的步骤，为空表示成功
+		wantCalls       []string
+		wantCompensated []string
+		wantUndoErrors  []string
+	}{
+		{
+			name:            "success",
+			createReturnsNo: true,
+			wantCalls:       []string{"OMS_EXPRESS_ORDER_CREATE", "STO_TRACE_PLATFORM_SUBSCRIBE"},
+		},
+		{
+			name:      "create order fails",
+			fail:      map[string]bool{"OMS_EXPRESS_ORDER_CREATE": true},
+			wantStep:  sto.FulfillStepCreateOrder,
+			wantCalls: []string{"OMS_EXPRESS_ORDER_CREATE"},
+		},
+		{
+			name:            "create order outcome unknown",
+			createGarbled:   true,
+			wantStep:        sto.FulfillStepCreateOrder,
+			wantCalls:       []string{"OMS_EXPRESS_ORDER_CREATE", "OMS_EXPRESS_ORDER_CANCEL"},
+			wantCompensated: []string{sto.FulfillStepCreateOrder},
+		},
+		{
+			name:           "create order outcome unknown and cancel fails",
+			createGarbled:  true,
+			fail:           map[string]bool{"OMS_EXPRESS_ORDER_CANCEL": true},
+			wantStep:       sto.FulfillStepCreateOrder,
+			wantCalls:      []string{"OMS_EXPRESS_ORDER_CREATE", "OMS_EXPRESS_ORDER_CANCEL"},
+			wantUndoErrors: []string{sto.FulfillStepCreateOrder},
+		},
+		{
+			name:            "query order fails",
+			fail:            map[string]bool{"OMS_EXPRESS_ORDER_QUERY": true},
+			wantStep:        sto.FulfillStepWaybillNo,
+			wantCalls:       []string{"OMS_EXPRESS_ORDER_CREATE", "OMS_EXPRESS_ORDER_QUERY", "OMS_EXPRESS_ORDER_CANCEL"},
+			wantCompensated: []string{sto.FulfillStepCreateOrder},
+		},
+		{
+			name:            "subscribe fails with waybill from create",
+			createReturnsNo: true,
+			fail:            map[string]bool{"STO_TRACE_PLATFORM_SUBSCRIBE": true},
+			wantStep:        sto.FulfillStepSubscribe,
+			wantCalls:       []string{"OMS_EXPRESS_ORDER_CREATE", "STO_TRACE_PLATFORM_SUBSCRIBE", "OMS_EXPRESS_ORDER_CANCEL"},
+			wantCompensated: []string{sto.FulfillStepCreateOrder},
+		},
+		{
+			name:     "subscribe fails with waybill from query",
+			fail:     map[string]bool{"STO_TRACE_PLATFORM_SUBSCRIBE": true},
+			wantStep: sto.FulfillStepSubscribe,
+			wantCalls: []string{
+				"OMS_EXPRESS_ORDER_CREATE", "OMS_EXPRESS_ORDER_QUERY", "STO_TRACE_PLATFORM_SUBSCRIBE",
+				"STO_WAYBILL_NO_RETURN", "OMS_EXPRESS_ORDER_CANCEL",
+			},
+			wantCompensated: []string{sto.FulfillStepWaybillNo, sto.FulfillStepCreateOrder},
+		},
+		{
+			name:     "compensation fails",
+			fail:     map[string]bool{"STO_TRACE_PLATFORM_SUBSCRIBE": true, "OMS_EXPRESS_ORDER_CANCEL": true},
+			wantStep: sto.FulfillStepSubscribe,
+			wantCalls: []string{
+				"OMS_EXPRESS_ORDER_CREATE", "OMS_EXPRESS_ORDER_QUERY", "STO_TRACE_PLATFORM_SUBSCRIBE",
+				"STO_WAYBILL_NO_RETURN", "OMS_EXPRESS_ORDER_CANCEL",
+			},
+			wantCompensated: []string{sto.FulfillStepWaybillNo},
+			wantUndoErrors:  []string{sto.FulfillStepCreateOrder},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			gw := stotest.NewGateway(testAppSecret)
+			handle := func(api string, data interface{}) {
+				gw.Handle(api, func(json.RawMessage) (interface{}, error) {
+					calls = append(calls, api)
+					if tt.fail[api] {
+						return nil, rejected
+					}
+					return data, nil
+				})
+			}
+			var created interface{} = &sto.OrderCreateResult{OrderNo: orderNo}
+			if tt.createReturnsNo {
+				created.(*sto.OrderCreateResult).WaybillNo = waybillNo
+			}
+			if tt.createGarbled {
+				created = []string{"garbled"}
+			}
+			handle("OMS_EXPRESS_ORDER_CREATE", created)
+			handle("OMS_EXPRESS_ORDER_QUERY", &sto.OrderInfo{OrderNo: orderNo, WaybillNo: waybillNo})
+			handle("OMS_EXPRESS_ORDER_CANCEL", nil)
+			handle("STO_WAYBILL_NO_RETURN", nil)
+			handle("STO_TRACE_PLATFORM_SUBSCRIBE", nil)
+			client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+			result, err := client.FulfillShipment(context.Background(), testOrder(orderNo))
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if tt.wantStep == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.WaybillNo != waybillNo || !result.Subscribed {
+					t.Errorf("result = %+v, want subscribed waybill %s", result, waybillNo)
+				}
+				return
+			}
+
+			var sagaErr *sto.SagaError
+			if !errors.As(err, &sagaErr) {
+				t.Fatalf("err = %v, want *sto.SagaError", err)
+			}
+			if sagaErr.Step != tt.wantStep {
+				t.Errorf("Step = %s, want %s", sagaErr.Step, tt.wantStep)
+			}
+			if len(sagaErr.Compensated)+len(tt.wantCompensated) > 0 && !reflect.DeepEqual(sagaErr.Compensated, tt.wantCompensated) {
+				t.Errorf("Compensated = %v, want %v", sagaErr.Compensated, tt.wantCompensated)
+			}
+			var undoErrors []string
+			for _, e := range sagaErr.CompensationErrors {
+				undoErrors = append(undoErrors, e.Step)
+			}
+			if !reflect.DeepEqual(undoErrors, tt.wantUndoErrors) {
+				t.Errorf("CompensationErrors = %v, want %v", undoErrors, tt.wantUndoErrors)
+			}
+		})
+	}
+}
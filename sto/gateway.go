@@ -0,0 +1,323 @@
+package sto
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// endpoint 网关接口定义
+type endpoint struct {
+	apiName  string // api_name
+	toAppKey string // to_appkey
+	toCode   string // to_code
+
+	emptyFields   emptyFieldPolicy // 请求内容中空字段的序列化方式
+	encryptFields bool             // 是否支持加密标记为sto:"encrypt"的字段，见WithFieldEncryption
+}
+
+// BaseResponse 网关响应的公共字段
+type BaseResponse struct {
+	Success   string `json:"success"`   // 是否成功
+	ErrorCode string `json:"errorCode"` // 错误码
+	ErrorMsg  string `json:"errorMsg"`  // 错误信息
+	NeedRetry string `json:"needRetry"` // 是否需要重试
+	RequestId string `json:"requestId"` // 请求ID
+	ExpInfo   string `json:"expInfo"`   // 异常信息
+
+	apiName  string        // 接口名称
+	attempts int           // 实际请求次数
+	elapsed  time.Duration // 总耗时
+	latency  time.Duration // 本次响应所在那次尝试的耗时，不含之前的重试和退避等待
+}
+
+// IsSuccess 检查是否成功
+func (r *BaseResponse) IsSuccess() bool {
+	return r.Success == "true"
+}
+
+// ShouldRetry 检查是否需要重试
+func (r *BaseResponse) ShouldRetry() bool {
+	return r.NeedRetry == "true"
+}
+
+// Err 网关返回失败时返回*APIError，成功时返回nil
+func (r *BaseResponse) Err() error {
+	if r.IsSuccess() {
+		return nil
+	}
+	return &APIError{
+		APIName:   r.apiName,
+		ErrorCode: r.ErrorCode,
+		ErrorMsg:  r.ErrorMsg,
+		NeedRetry: r.ShouldRetry(),
+		RequestId: r.RequestId,
+		Attempts:  r.attempts,
+		Elapsed:   r.elapsed,
+	}
+}
+
+// rawResponse 网关响应，data字段保留原始JSON，由各接口自行解析
+type rawResponse struct {
+	BaseResponse
+	Data json.RawMessage `json:"data"`
+
+	attempt int // 第几次尝试得到的响应，从0开始
+}
+
+// decodeData 解析响应的data字段，data为空时不做处理。
+// v为*json.RawMessage时直接复制原始内容，不再解析
+func (c *Client) decodeData(resp *rawResponse, v interface{}) error {
+	raw := resp.Data
+	if len(raw) == 0 || v == nil {
+		return nil
+	}
+	if p, ok := v.(*json.RawMessage); ok {
+		*p = append((*p)[:0], raw...)
+		return nil
+	}
+	if c.amountCodec.Strict {
+		if err := checkStrictAmounts(raw, v); err != nil {
+			return &decodeError{fmt.Errorf("unmarshal response data failed: %w, requestId: %s", err, resp.RequestId)}
+		}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &decodeError{fmt.Errorf("unmarshal response data failed: %w, requestId: %s%s", err, resp.RequestId, c.bodyDetail(raw))}
+	}
+	return nil
+}
+
+// callResult 一次接口调用（含重试）的结果
+type callResult struct {
+	resp     *rawResponse   // 最后一次尝试的响应，最后一次请求失败时为nil
+	attempts []*rawResponse // 各次尝试中成功解析的响应，按尝试顺序排列
+	retries  int            // 重试次数
+	elapsed  time.Duration  // 总耗时
+}
+
+// execute 签名并调用网关接口，按配置进行重试。
+// 请求失败或重试用尽后网关仍要求重试时返回*APIError，此时最后一次的响应（如有）仍会返回
+func (c *Client) execute(ctx context.Context, ep endpoint, req interface{}) (*callResult, error) {
+	if c.transportErr != nil {
+		return nil, &APIError{APIName: ep.apiName, Err: c.transportErr}
+	}
+
+	// 将请求内容转为JSON
+	content, err := c.marshalContent(ctx, ep, req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request failed: %w", err)
+	}
+
+	var probe bool
+	if c.breaker != nil {
+		var ok bool
+		if ok, probe = c.breaker.allow(); !ok {
+			return nil, &APIError{APIName: ep.apiName, Err: ErrCircuitOpen}
+		}
+	}
+
+	// 生成data_digest
+	h := md5.New()
+	h.Write([]byte(string(content) + c.AppSecret))
+	dataDigest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	// 构建请求参数
+	params := url.Values{}
+	params.Add("content", string(content))
+	params.Add("data_digest", dataDigest)
+	params.Add("from_appkey", c.AppKey)
+	params.Add("from_code", c.FromCode)
+	params.Add("to_appkey", ep.toAppKey)
+	params.Add("to_code", ep.toCode)
+	params.Add("api_name", ep.apiName)
+
+	// 构建完整URL
+	requestURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
+
+	result := &callResult{}
+	var lastErr error
+	start := time.Now()
+
+	// 重试逻辑
+retry:
+	for i := 0; i <= c.maxRetries; i++ {
+		if i > 0 {
+			result.retries = i
+			if c.Debug {
+				c.logf("Retrying request (attempt %d/%d)", i, c.maxRetries)
+			}
+		}
+
+		attemptStart := time.Now()
+		result.resp, lastErr = c.doRequest(ctx, requestURL, content, dataDigest)
+		if result.resp != nil {
+			result.resp.attempt = i
+			result.resp.latency = time.Since(attemptStart)
+			result.attempts = append(result.attempts, result.resp)
+		}
+		if lastErr == nil && !result.resp.ShouldRetry() {
+			break
+		}
+
+		if i < c.maxRetries {
+			// 简单的退避策略
+			select {
+			case <-ctx.Done():
+				lastErr = ctx.Err()
+				break retry
+			case <-time.After(time.Duration(i+1) * time.Second):
+			}
+		}
+	}
+
+	result.elapsed = time.Since(start)
+	var last *BaseResponse
+	if n := len(result.attempts); n > 0 {
+		last = &result.attempts[n-1].BaseResponse
+	}
+	if result.resp != nil {
+		result.resp.apiName = ep.apiName
+		result.resp.attempts = result.retries + 1
+		result.resp.elapsed = result.elapsed
+	}
+
+	if lastErr != nil || result.resp.ShouldRetry() {
+		apiErr := &APIError{
+			APIName:   ep.apiName,
+			Attempts:  result.retries + 1,
+			Elapsed:   result.elapsed,
+			Exhausted: result.retries > 0 && result.retries == c.maxRetries && ctx.Err() == nil,
+			Err:       lastErr,
+		}
+		if last != nil {
+			apiErr.ErrorCode = last.ErrorCode
+			apiErr.ErrorMsg = last.ErrorMsg
+			apiErr.NeedRetry = last.ShouldRetry()
+			apiErr.RequestId = last.RequestId
+		}
+		lastErr = apiErr
+	}
+
+	if c.breaker != nil {
+		// 调用方主动取消不说明网关异常，不计入结果；ctx超时说明网关响应过慢，按失败计入
+		if errors.Is(ctx.Err(), context.Canceled) {
+			c.breaker.release(probe)
+		} else {
+			c.breaker.record(lastErr == nil, probe)
+		}
+	}
+
+	var requestID string
+	if last != nil {
+		requestID = last.RequestId
+	}
+	c.logSlowRequest(ep.apiName, requestID, result.retries, result.elapsed)
+
+	return result, lastErr
+}
+
+// Request 通用接口调用参数，用于SDK尚未封装的接口
+type Request struct {
+	APIName  string      // api_name
+	ToAppKey string      // to_appkey
+	ToCode   string      // to_code，为空时与ToAppKey相同
+	Content  interface{} // 请求内容，序列化为JSON；已序列化的内容可使用json.RawMessage
+}
+
+// Execute 调用任意网关接口，并将响应的data字段解析到data中。
+// data可以是结构体指针、map指针或*json.RawMessage（原样保留），为nil时忽略data字段
+func (c *Client) Execute(ctx context.Context, req *Request, data interface{}) (*BaseResponse, error) {
+	if req.APIName == "" || req.ToAppKey == "" {
+		return nil, &InvalidRequestError{Err: fmt.Errorf("apiName and toAppKey cannot be empty")}
+	}
+
+	ep := endpoint{apiName: req.APIName, toAppKey: req.ToAppKey, toCode: req.ToCode}
+	if ep.toCode == "" {
+		ep.toCode = ep.toAppKey
+	}
+
+	res, err := c.execute(ctx, ep, req.Content)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := res.resp.BaseResponse
+	if uerr := c.decodeData(res.resp, data); uerr != nil {
+		return nil, uerr
+	}
+
+	return &resp, err
+}
+
+// logSlowRequest 记录超过阈值的慢请求
+func (c *Client) logSlowRequest(apiName, requestID string, retries int, elapsed time.Duration) {
+	if c.slowThreshold <= 0 || elapsed < c.slowThreshold {
+		return
+	}
+	c.logf("slow request: api_name=%s requestId=%s retries=%d elapsed=%s threshold=%s",
+		apiName, requestID, retries, elapsed, c.slowThreshold)
+}
+
+// doRequest 执行HTTP请求
+func (c *Client) doRequest(ctx context.Context, requestURL string, content []byte, dataDigest string) (*rawResponse, error) {
+	if c.Debug {
+		c.logf("Request URL: %s", requestURL)
+		c.logf("Content: %s", string(content))
+		c.logf("Data Digest: %s", dataDigest)
+	}
+
+	// 创建请求
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+	if c.strictTLS() && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("refusing plaintext request to %s", req.URL.Host)
+	}
+
+	// 设置请求头
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+
+	// 发送请求
+	c.mu.RLock()
+	client := c.httpClient
+	debug := c.Debug
+	c.mu.RUnlock()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		c.recordUsage(len(requestURL), 0, true)
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	c.recordUsage(len(requestURL), len(body), err != nil)
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	if debug {
+		c.logf("Response Status: %d", resp.StatusCode)
+		c.logf("Response Body: %s", string(body))
+	}
+
+	// 检查状态码
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned non-200 status code: %d%s", resp.StatusCode, c.bodyDetail(body))
+	}
+
+	var result rawResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal response failed: %v%s", err, c.bodyDetail(body))
+	}
+
+	return &result, nil
+}
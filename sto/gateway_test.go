@@ -0,0 +1,215 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+// captureLogger 记录日志内容的Logger
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// Printf 实现sto.Logger接口
+func (l *captureLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+// contains 返回包含substr的日志行
+func (l *captureLogger) contains(substr string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var matched []string
+	for _, line := range l.lines {
+		if strings.Contains(line, substr) {
+			matched = append(matched, line)
+		}
+	}
+	return matched
+}
+
+// delayed 等待d后返回固定JSON响应
+func delayed(d time.Duration, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(d)
+		rawJSON(body)(w, r)
+	}
+}
+
+func TestSlowRequestLogging(t *testing.T) {
+	const ok = `{"success":"true","needRetry":"false","requestId":"req-1","data":{}}`
+
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		delay     time.Duration
+		wantLog   bool
+	}{
+		{name: "disabled", delay: 20 * time.Millisecond},
+		{name: "fast request", threshold: time.Second},
+		{name: "slow request", threshold: 10 * time.Millisecond, delay: 20 * time.Millisecond, wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &captureLogger{}
+			client := newTestClient(t, sequence(delayed(tt.delay, ok)),
+				sto.WithLogger(logger), sto.WithSlowRequestThreshold(tt.threshold))
+			if _, err := client.QueryTraceContext(context.Background(), &sto.TraceQueryRequest{WaybillNoList: []string{"A"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			lines := logger.contains("slow request")
+			if (len(lines) > 0) != tt.wantLog {
+				t.Fatalf("slow request logs = %q, want logged %v", lines, tt.wantLog)
+			}
+			if tt.wantLog {
+				for _, want := range []string{"api_name=STO_TRACE_QUERY_COMMON", "requestId=req-1", "retries=0", "threshold=10ms"} {
+					if !strings.Contains(lines[0], want) {
+						t.Errorf("log %q missing %q", lines[0], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	const content = `{"orderNo":"ORDER-1","items":[{"sku":"A","qty":2}],"memo":null}`
+	const data = `{"waybillNo":"773000000001","extra":{"nested":[1,2,3]}}`
+
+	tests := []struct {
+		name      string
+		req       *sto.Request
+		data      func() interface{} // 传给Execute的data参数
+		reply     interface{}        // 模拟网关返回的data
+		wantCode  string             // ErrorCodeOf的结果
+		wantToApp string             // 网关收到的to_appkey
+		wantTo    string             // 网关收到的to_code
+		check     func(*testing.T, interface{})
+	}{
+		{
+			name:      "raw content and raw data pass through",
+			req:       &sto.Request{APIName: "CUSTOM_API", ToAppKey: "custom_app", Content: json.RawMessage(content)},
+			data:      func() interface{} { return new(json.RawMessage) },
+			reply:     json.RawMessage(data),
+			wantToApp: "custom_app",
+			wantTo:    "custom_app",
+			check: func(t *testing.T, v interface{}) {
+				if got := string(*v.(*json.RawMessage)); got != data {
+					t.Errorf("data = %s, want %s", got, data)
+				}
+			},
+		},
+		{
+			name:      "data decoded into struct",
+			req:       &sto.Request{APIName: "CUSTOM_API", ToAppKey: "custom_app", ToCode: "custom_code", Content: json.RawMessage(content)},
+			data:      func() interface{} { return new(struct{ WaybillNo string }) },
+			reply:     json.RawMessage(data),
+			wantToApp: "custom_app",
+			wantTo:    "custom_code",
+			check: func(t *testing.T, v interface{}) {
+				if got := v.(*struct{ WaybillNo string }).WaybillNo; got != "773000000001" {
+					t.Errorf("WaybillNo = %q", got)
+				}
+			},
+		},
+		{
+			name:      "nil data ignores response data",
+			req:       &sto.Request{APIName: "CUSTOM_API", ToAppKey: "custom_app", Content: json.RawMessage(content)},
+			data:      func() interface{} { return nil },
+			reply:     json.RawMessage(data),
+			wantToApp: "custom_app",
+			wantTo:    "custom_app",
+		},
+		{
+			name:     "undecodable data",
+			req:      &sto.Request{APIName: "CUSTOM_API", ToAppKey: "custom_app", Content: json.RawMessage(content)},
+			data:     func() interface{} { return new([]string) },
+			reply:    json.RawMessage(data),
+			wantCode: sto.ErrorCodeDecode,
+		},
+		{
+			name:     "missing api name",
+			req:      &sto.Request{ToAppKey: "custom_app"},
+			data:     func() interface{} { return nil },
+			wantCode: sto.ErrorCodeValidation,
+		},
+		{
+			name:     "missing to_appkey",
+			req:      &sto.Request{APIName: "CUSTOM_API"},
+			data:     func() interface{} { return nil },
+			wantCode: sto.ErrorCodeValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotContent string
+			var gotForm url.Values
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("CUSTOM_API", func(c json.RawMessage) (interface{}, error) {
+				gotContent = string(c)
+				return tt.reply, nil
+			})
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				gotForm = r.Form
+				gw.ServeHTTP(w, r)
+			})
+			client := newTestClient(t, h, sto.WithMaxRetries(0))
+
+			v := tt.data()
+			resp, err := client.Execute(context.Background(), tt.req, v)
+			if got := sto.ErrorCodeOf(err); got != tt.wantCode {
+				t.Fatalf("ErrorCodeOf(%v) = %q, want %q", err, got, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if !resp.IsSuccess() || resp.RequestId == "" {
+				t.Errorf("resp = %+v, want success with requestId", resp)
+			}
+			if gotContent != content {
+				t.Errorf("content = %s, want %s", gotContent, content)
+			}
+			if gotForm.Get("to_appkey") != tt.wantToApp || gotForm.Get("to_code") != tt.wantTo {
+				t.Errorf("to_appkey = %q, to_code = %q, want %q, %q", gotForm.Get("to_appkey"), gotForm.Get("to_code"), tt.wantToApp, tt.wantTo)
+			}
+			if tt.check != nil {
+				tt.check(t, v)
+			}
+		})
+	}
+}
+
+func TestExecuteBusinessError(t *testing.T) {
+	gw := stotest.NewGateway(testAppSecret)
+	gw.Handle("CUSTOM_API", func(json.RawMessage) (interface{}, error) {
+		return nil, &sto.APIError{ErrorCode: sto.ErrorCodeInvalidParam, ErrorMsg: "invalid"}
+	})
+	client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+	resp, err := client.Execute(context.Background(), &sto.Request{APIName: "CUSTOM_API", ToAppKey: "custom_app"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apiErr *sto.APIError
+	if !errors.As(resp.Err(), &apiErr) || apiErr.ErrorCode != sto.ErrorCodeInvalidParam || apiErr.APIName != "CUSTOM_API" {
+		t.Errorf("resp.Err() = %v, want invalid param from CUSTOM_API", resp.Err())
+	}
+}
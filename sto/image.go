@@ -0,0 +1,145 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// MaxScanImageSize 下载轨迹图片的大小上限，超过时DownloadScanImage返回错误
+const MaxScanImageSize = 10 << 20
+
+// scanImageEndpoint 轨迹图片查询接口
+var scanImageEndpoint = endpoint{
+	apiName:  "STO_TRACE_IMAGE_QUERY",
+	toAppKey: "sto_trace_image",
+	toCode:   "sto_trace_image",
+
+	emptyFields: emptyFieldsOmit,
+}
+
+// ScanImageQueryRequest 轨迹图片查询请求参数
+type ScanImageQueryRequest struct {
+	WaybillNo string `json:"waybillNo"` // 运单号
+	ScanType  string `json:"scanType"`  // 扫描类型，为空表示全部，如"问题件"、"签收"
+}
+
+// Validate 验证请求参数
+func (r *ScanImageQueryRequest) Validate() error {
+	if r.WaybillNo == "" {
+		return fmt.Errorf("waybillNo cannot be empty")
+	}
+	return nil
+}
+
+// ScanImage 轨迹图片（如问题件照片、签收底单）
+type ScanImage struct {
+	WaybillNo   string `json:"waybillNo"`   // 运单号
+	ScanType    string `json:"scanType"`    // 扫描类型
+	OpTime      string `json:"opTime"`      // 拍摄时间
+	ImageURL    string `json:"imageUrl"`    // 图片地址，过期后无法访问
+	ContentType string `json:"contentType"` // 图片类型，如image/jpeg
+	ExpireTime  string `json:"expireTime"`  // 图片地址过期时间
+}
+
+// ExpiresAt 返回图片地址的过期时间，接口未返回过期时间时ok为false
+func (img ScanImage) ExpiresAt() (t time.Time, ok bool) {
+	if img.ExpireTime == "" {
+		return time.Time{}, false
+	}
+	t, err := ParseTime(img.ExpireTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Expired 判断图片地址在now时是否已过期
+func (img ScanImage) Expired(now time.Time) bool {
+	t, ok := img.ExpiresAt()
+	return ok && !now.Before(t)
+}
+
+// MediaType 返回图片类型，接口未返回时根据地址的扩展名推断
+func (img ScanImage) MediaType() string {
+	if img.ContentType != "" {
+		return img.ContentType
+	}
+	if u, err := url.Parse(img.ImageURL); err == nil {
+		if t := mime.TypeByExtension(path.Ext(u.Path)); t != "" {
+			return t
+		}
+	}
+	return "application/octet-stream"
+}
+
+// ScanImageQueryResponse 轨迹图片查询响应
+type ScanImageQueryResponse struct {
+	BaseResponse
+	Data []ScanImage `json:"data"` // 图片列表
+}
+
+// QueryScanImages 查询运单的轨迹图片，用于问题件处理和理赔举证
+func (c *Client) QueryScanImages(ctx context.Context, req *ScanImageQueryRequest) (*ScanImageQueryResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, scanImageEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &ScanImageQueryResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
+
+// DownloadScanImage 下载轨迹图片，返回图片内容和类型。图片地址已过期时直接返回错误，需重新查询。
+// 图片不在网关域名下，下载时不使用WithResolver、WithStaticHosts和证书固定等网关连接设置；
+// 图片超过MaxScanImageSize时返回错误
+func (c *Client) DownloadScanImage(ctx context.Context, img ScanImage) ([]byte, string, error) {
+	if img.Expired(time.Now()) {
+		return nil, "", fmt.Errorf("image url expired at %s, query again", img.ExpireTime)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", img.ImageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("create request failed: %v", err)
+	}
+
+	resp, err := c.imageClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("image returned non-200 status code: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > MaxScanImageSize {
+		return nil, "", fmt.Errorf("image size %d exceeds limit %d", resp.ContentLength, MaxScanImageSize)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxScanImageSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("read image failed: %v", err)
+	}
+	if len(body) > MaxScanImageSize {
+		return nil, "", fmt.Errorf("image size exceeds limit %d", MaxScanImageSize)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = img.MediaType()
+	}
+	return body, contentType, nil
+}
@@ -0,0 +1,74 @@
+package sto_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+func TestDownloadScanImage(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	large := bytes.Repeat([]byte{0}, sto.MaxScanImageSize+1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	})
+	mux.HandleFunc("/large.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+		w.Write(large)
+	})
+	mux.HandleFunc("/chunked.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(large[:1024])
+		w.(http.Flusher).Flush()
+		w.Write(large[1024:])
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// 网关的静态解析不应影响图片下载：若被应用，连接会被导向未监听的地址
+	client := sto.NewClient(testAppKey, testAppSecret, testFromCode,
+		sto.WithStaticHosts(map[string][]string{"127.0.0.1": {"127.0.0.2"}}),
+		sto.WithLogger(discardLogger{}),
+	)
+
+	tests := []struct {
+		name     string
+		img      sto.ScanImage
+		wantData []byte
+		wantType string
+		wantErr  bool
+	}{
+		{"ok", sto.ScanImage{ImageURL: srv.URL + "/ok.png"}, png, "image/png", false},
+		{"content length over limit", sto.ScanImage{ImageURL: srv.URL + "/large.jpg"}, nil, "", true},
+		{"streamed over limit", sto.ScanImage{ImageURL: srv.URL + "/chunked.jpg"}, nil, "", true},
+		{"not found", sto.ScanImage{ImageURL: srv.URL + "/missing.png"}, nil, "", true},
+		{
+			name: "expired",
+			img: sto.ScanImage{
+				ImageURL:   srv.URL + "/ok.png",
+				ExpireTime: sto.FormatTime(time.Now().Add(-time.Hour)),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := client.DownloadScanImage(context.Background(), tt.img)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(data, tt.wantData) || contentType != tt.wantType {
+				t.Errorf("got %d bytes (%s), want %d bytes (%s)", len(data), contentType, len(tt.wantData), tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package sto
+
+import "fmt"
+
+// Logger 日志接口，标准库 *log.Logger 即满足该接口
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// stdoutLogger 默认日志实现，输出到标准输出
+type stdoutLogger struct{}
+
+// Printf 输出一行日志
+func (stdoutLogger) Printf(format string, v ...interface{}) {
+	fmt.Printf(format+"\n", v...)
+}
+
+// logf 通过客户端配置的Logger输出日志
+func (c *Client) logf(format string, v ...interface{}) {
+	c.logger.Printf(format, v...)
+}
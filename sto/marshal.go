@@ -0,0 +1,279 @@
+package sto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// emptyFieldPolicy 请求内容中空字段（null或空字符串）的序列化方式
+type emptyFieldPolicy int
+
+const (
+	// emptyFieldsKeep 保持encoding/json的默认输出
+	emptyFieldsKeep emptyFieldPolicy = iota
+
+	// emptyFieldsOmit 去掉值为null或空字符串的字段，用于拒绝空字段的接口
+	emptyFieldsOmit
+
+	// emptyFieldsExplicit 带omitempty标签、被encoding/json省略的字段也以原值（空字符串、0、null等）输出，
+	// 用于要求字段必须存在的接口
+	emptyFieldsExplicit
+)
+
+// marshalContent 按接口定义的空字段规则序列化请求内容。接口支持加密字段且设置了WithFieldEncryption时加密标记的字段，
+// 设置了WithAmountCodec且EncodeAsString时金额字段序列化为字符串
+func (c *Client) marshalContent(ctx context.Context, ep endpoint, req interface{}) ([]byte, error) {
+	content, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	encrypt := ep.encryptFields && c.fieldEncryptor != nil && c.fieldKeys != nil
+	var amountPaths [][]string
+	if c.amountCodec.EncodeAsString {
+		amountPaths = fieldPaths(reflect.TypeOf(req), amountFields)
+	}
+	if ep.emptyFields == emptyFieldsKeep && !encrypt && len(amountPaths) == 0 {
+		return content, nil
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode request content failed: %v", err)
+	}
+	if ep.emptyFields == emptyFieldsExplicit {
+		if err := restoreOmittedFields(v, reflect.ValueOf(req)); err != nil {
+			return nil, fmt.Errorf("restore omitted fields failed: %v", err)
+		}
+	}
+	for _, path := range amountPaths {
+		walkFieldPath(v, path, func(obj map[string]interface{}, key string) error {
+			if n, ok := obj[key].(json.Number); ok {
+				obj[key] = n.String()
+			}
+			return nil
+		})
+	}
+	if encrypt {
+		key, err := c.fieldKeys.Key(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get encryption key failed: %w", err)
+		}
+		for _, path := range fieldPaths(reflect.TypeOf(req), encryptedFields) {
+			if err := encryptFields(v, path, key, c.fieldEncryptor); err != nil {
+				return nil, fmt.Errorf("encrypt request content failed: %w", err)
+			}
+		}
+	}
+	return json.Marshal(applyEmptyFieldPolicy(v, ep.emptyFields))
+}
+
+// applyEmptyFieldPolicy 递归处理对象中的空字段
+func applyEmptyFieldPolicy(v interface{}, policy emptyFieldPolicy) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, field := range val {
+			if (field == nil || field == "") && policy == emptyFieldsOmit {
+				delete(val, k)
+			} else {
+				val[k] = applyEmptyFieldPolicy(field, policy)
+			}
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = applyEmptyFieldPolicy(val[i], policy)
+		}
+	}
+	return v
+}
+
+// restoreOmittedFields 按请求的Go值把因omitempty被省略的字段补回解码后的JSON对象，
+// 补回的值与该字段单独序列化的结果一致，不经过map
+func restoreOmittedFields(v interface{}, rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		if obj, ok := v.(map[string]interface{}); ok {
+			return restoreStructFields(obj, rv)
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := v.([]interface{}); ok {
+			for i := 0; i < len(arr) && i < rv.Len(); i++ {
+				if err := restoreOmittedFields(arr[i], rv.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// restoreStructFields 补回结构体中被省略的字段，嵌入的结构体字段在JSON中展开到外层
+func restoreStructFields(obj map[string]interface{}, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && name == "" {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := restoreStructFields(obj, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		field, ok := obj[name]
+		if ok {
+			if err := restoreOmittedFields(field, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if !hasTagOption(tag[1:], "omitempty") {
+			continue
+		}
+		b, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&field); err != nil {
+			return err
+		}
+		obj[name] = field
+	}
+	return nil
+}
+
+// hasTagOption 检查json标签是否包含指定选项
+func hasTagOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldKind 序列化后需要按路径处理的字段类别
+type fieldKind int
+
+const (
+	encryptedFields fieldKind = iota // 标记为sto:"encrypt"的字符串字段
+	amountFields                     // Amount类型的字段
+)
+
+// fieldPathKey 字段路径缓存的键
+type fieldPathKey struct {
+	t    reflect.Type
+	kind fieldKind
+}
+
+// fieldPathCache 类型和字段类别 -> 字段的JSON路径
+var fieldPathCache sync.Map
+
+// fieldPaths 返回类型中指定类别字段的JSON路径，路径经过切片时对每个元素生效，不经过map
+func fieldPaths(t reflect.Type, kind fieldKind) [][]string {
+	key := fieldPathKey{t, kind}
+	if cached, ok := fieldPathCache.Load(key); ok {
+		return cached.([][]string)
+	}
+	match := isEncryptedField
+	if kind == amountFields {
+		match = isAmountField
+	}
+	paths := collectFieldPaths(t, nil, make(map[reflect.Type]bool), match)
+	fieldPathCache.Store(key, paths)
+	return paths
+}
+
+// collectFieldPaths 递归收集满足match的字段路径，visiting用于避免递归类型死循环
+func collectFieldPaths(t reflect.Type, prefix []string, visiting map[reflect.Type]bool, match func(reflect.StructField) bool) [][]string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var paths [][]string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// 嵌入的结构体字段在JSON中展开到外层
+			paths = append(paths, collectFieldPaths(f.Type, prefix, visiting, match)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		path := append(append([]string(nil), prefix...), name)
+		if match(f) {
+			paths = append(paths, path)
+			continue
+		}
+		paths = append(paths, collectFieldPaths(f.Type, path, visiting, match)...)
+	}
+	return paths
+}
+
+// walkFieldPath 在解码后的JSON中找到path指向的字段，对每个存在的字段调用fn，路径经过数组时对每个元素生效
+func walkFieldPath(v interface{}, path []string, fn func(obj map[string]interface{}, key string) error) error {
+	switch val := v.(type) {
+	case []interface{}:
+		for _, item := range val {
+			if err := walkFieldPath(item, path, fn); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		field, ok := val[path[0]]
+		if !ok {
+			return nil
+		}
+		if len(path) > 1 {
+			return walkFieldPath(field, path[1:], fn)
+		}
+		return fn(val, path[0])
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sto
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalContentEmptyFields(t *testing.T) {
+	type item struct {
+		Name string  `json:"name"`
+		Memo *string `json:"memo"`
+		Note string  `json:"note,omitempty"`
+	}
+	type request struct {
+		WaybillNo string   `json:"waybillNo"`
+		Reason    string   `json:"reason"`
+		Items     []item   `json:"items"`
+		Tags      []string `json:"tags"`
+		Count     int      `json:"count"`
+		Remark    string   `json:"remark,omitempty"`
+		Extra     *item    `json:"extra,omitempty"`
+	}
+	req := request{
+		WaybillNo: "773000000001",
+		Items:     []item{{Name: "a"}},
+		Count:     0,
+	}
+
+	tests := []struct {
+		name   string
+		policy emptyFieldPolicy
+		want   string
+	}{
+		{
+			name:   "keep",
+			policy: emptyFieldsKeep,
+			want:   `{"waybillNo":"773000000001","reason":"","items":[{"name":"a","memo":null}],"tags":null,"count":0}`,
+		},
+		{
+			name:   "omit",
+			policy: emptyFieldsOmit,
+			want:   `{"count":0,"items":[{"name":"a"}],"waybillNo":"773000000001"}`,
+		},
+		{
+			name:   "explicit",
+			policy: emptyFieldsExplicit,
+			want:   `{"count":0,"extra":null,"items":[{"memo":null,"name":"a","note":""}],"reason":"","remark":"","tags":null,"waybillNo":"773000000001"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&Client{}).marshalContent(context.Background(), endpoint{emptyFields: tt.policy}, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalContentOrderCreateKeepsOmittedFields(t *testing.T) {
+	req := &OrderCreateRequest{
+		OrderNo:  "ORDER-1",
+		Sender:   OrderContact{Name: "张三", Mobile: "13800000000", IDNo: "110101199001011234"},
+		Receiver: OrderContact{Name: "李四", Mobile: "13900000000"},
+	}
+	got, err := (&Client{}).marshalContent(context.Background(), orderCreateEndpoint, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var content struct {
+		Sender   map[string]interface{} `json:"sender"`
+		Receiver map[string]interface{} `json:"receiver"`
+	}
+	if err := json.Unmarshal(got, &content); err != nil {
+		t.Fatal(err)
+	}
+	if idNo, ok := content.Receiver["idNo"]; !ok || idNo != "" {
+		t.Errorf("receiver idNo = %v (present %v), want empty string: %s", idNo, ok, got)
+	}
+	if idNo := content.Sender["idNo"]; idNo != req.Sender.IDNo {
+		t.Errorf("sender idNo = %v, want %s", idNo, req.Sender.IDNo)
+	}
+}
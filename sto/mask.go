@@ -0,0 +1,160 @@
+package sto
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaskPolicy 脱敏规则，保留的字符数按字符（rune）计算
+type MaskPolicy struct {
+	PhoneKeepPrefix   int  // 电话号码保留前几位
+	PhoneKeepSuffix   int  // 电话号码保留后几位
+	NameKeepPrefix    int  // 姓名保留前几个字
+	NameKeepSuffix    int  // 姓名保留后几个字
+	IDKeepPrefix      int  // 证件号码保留前几位
+	IDKeepSuffix      int  // 证件号码保留后几位
+	AddressKeepPrefix int  // 详细地址保留前几个字
+	MaskChar          rune // 脱敏替换字符
+	KeepCourierInfo   bool // 是否保留轨迹中的快递员姓名和电话，便于收件人联系派件员
+}
+
+// DefaultMaskPolicy 默认脱敏规则：138****1234、张*、110***********123X、详细地址保留前6个字
+var DefaultMaskPolicy = MaskPolicy{
+	PhoneKeepPrefix:   3,
+	PhoneKeepSuffix:   4,
+	NameKeepPrefix:    1,
+	IDKeepPrefix:      3,
+	IDKeepSuffix:      4,
+	AddressKeepPrefix: 6,
+	MaskChar:          '*',
+}
+
+// mobilePattern 文本中的手机号码
+var mobilePattern = regexp.MustCompile(`1[3-9]\d{9}`)
+
+// MaskPhone 对电话号码脱敏
+func (p MaskPolicy) MaskPhone(s string) string {
+	return maskRunes(s, p.PhoneKeepPrefix, p.PhoneKeepSuffix, p.maskChar())
+}
+
+// MaskName 对姓名脱敏
+func (p MaskPolicy) MaskName(s string) string {
+	return maskRunes(s, p.NameKeepPrefix, p.NameKeepSuffix, p.maskChar())
+}
+
+// MaskIDNo 对证件号码脱敏
+func (p MaskPolicy) MaskIDNo(s string) string {
+	return maskRunes(s, p.IDKeepPrefix, p.IDKeepSuffix, p.maskChar())
+}
+
+// MaskAddress 对详细地址脱敏
+func (p MaskPolicy) MaskAddress(s string) string {
+	return maskRunes(s, p.AddressKeepPrefix, 0, p.maskChar())
+}
+
+// MaskText 对文本中出现的手机号码脱敏，用于备注等自由文本
+func (p MaskPolicy) MaskText(s string) string {
+	return mobilePattern.ReplaceAllStringFunc(s, p.MaskPhone)
+}
+
+// maskChar 返回脱敏替换字符，未设置时为'*'
+func (p MaskPolicy) maskChar() rune {
+	if p.MaskChar == 0 {
+		return '*'
+	}
+	return p.MaskChar
+}
+
+// maskRunes 保留前prefix个和后suffix个字符，其余替换为ch，负数按0处理。
+// 字符串过短时依次减少保留的后缀和前缀，至少替换一个字符，保留的字符数不会超过规则设置
+func maskRunes(s string, prefix, suffix int, ch rune) string {
+	if s == "" {
+		return s
+	}
+	if prefix < 0 {
+		prefix = 0
+	}
+	if suffix < 0 {
+		suffix = 0
+	}
+	r := []rune(s)
+	if over := prefix + suffix - (len(r) - 1); over > 0 {
+		cut := over
+		if cut > suffix {
+			cut = suffix
+		}
+		suffix -= cut
+		prefix -= over - cut
+	}
+
+	var b strings.Builder
+	b.WriteString(string(r[:prefix]))
+	b.WriteString(strings.Repeat(string(ch), len(r)-prefix-suffix))
+	b.WriteString(string(r[len(r)-suffix:]))
+	return b.String()
+}
+
+// MaskPhone 使用默认规则对电话号码脱敏
+func MaskPhone(s string) string {
+	return DefaultMaskPolicy.MaskPhone(s)
+}
+
+// MaskName 使用默认规则对姓名脱敏
+func MaskName(s string) string {
+	return DefaultMaskPolicy.MaskName(s)
+}
+
+// Masked 返回脱敏后的轨迹副本：签收人、备注中的手机号码，以及（未设置KeepCourierInfo时）快递员姓名和电话
+func (t TraceInfo) Masked(p MaskPolicy) TraceInfo {
+	t.SignoffPeople = p.MaskName(t.SignoffPeople)
+	t.Memo = p.MaskText(t.Memo)
+	if !p.KeepCourierInfo {
+		t.OpEmpName = p.MaskName(t.OpEmpName)
+		t.BizEmpName = p.MaskName(t.BizEmpName)
+		t.BizEmpPhone = p.MaskPhone(t.BizEmpPhone)
+		t.BizEmpTel = p.MaskPhone(t.BizEmpTel)
+	}
+	return t
+}
+
+// MaskTraces 返回脱敏后的轨迹列表，不修改原数据
+func MaskTraces(traces []TraceInfo, p MaskPolicy) []TraceInfo {
+	if traces == nil {
+		return nil
+	}
+	masked := make([]TraceInfo, len(traces))
+	for i, t := range traces {
+		masked[i] = t.Masked(p)
+	}
+	return masked
+}
+
+// Masked 返回脱敏后的寄/收件人信息副本，省市区保持不变
+func (o OrderContact) Masked(p MaskPolicy) OrderContact {
+	o.Name = p.MaskName(o.Name)
+	o.Mobile = p.MaskPhone(o.Mobile)
+	o.Tel = p.MaskPhone(o.Tel)
+	o.IDNo = p.MaskIDNo(o.IDNo)
+	o.Address = p.MaskAddress(o.Address)
+	return o
+}
+
+// Masked 返回脱敏后的订单信息副本
+func (o OrderInfo) Masked(p MaskPolicy) OrderInfo {
+	o.Sender = o.Sender.Masked(p)
+	o.Receiver = o.Receiver.Masked(p)
+	return o
+}
+
+// Masked 返回脱敏后的发货状态副本
+func (s ShipmentStatus) Masked(p MaskPolicy) ShipmentStatus {
+	if s.Order != nil {
+		order := s.Order.Masked(p)
+		s.Order = &order
+	}
+	s.Traces = MaskTraces(s.Traces, p)
+	if n := len(s.Traces); n > 0 && s.LatestTrace != nil {
+		s.LatestTrace = &s.Traces[n-1]
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+package sto
+
+import "testing"
+
+func TestMaskRunes(t *testing.T) {
+	tests := []struct {
+		name           string
+		s              string
+		prefix, suffix int
+		want           string
+	}{
+		{"empty", "", 3, 4, ""},
+		{"phone", "13812341234", 3, 4, "138****1234"},
+		{"name", "张三丰", 1, 0, "张**"},
+		{"name keep both", "欧阳娜娜", 1, 1, "欧**娜"},
+		{"too short", "张三", 1, 1, "张*"},
+		{"single rune", "张", 1, 0, "*"},
+		{"too short keeps no more prefix", "张三", 0, 2, "*三"},
+		{"too short trims suffix first", "138", 3, 4, "13*"},
+		{"too short without keep", "张三", 0, 0, "**"},
+		{"no keep", "13812341234", 0, 0, "***********"},
+		{"negative prefix", "13812341234", -3, 4, "*******1234"},
+		{"negative suffix", "13812341234", 3, -4, "138********"},
+		{"both negative", "张三", -1, -1, "**"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskRunes(tt.s, tt.prefix, tt.suffix, '*'); got != tt.want {
+				t.Errorf("maskRunes(%q, %d, %d) = %q, want %q", tt.s, tt.prefix, tt.suffix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskPolicy(t *testing.T) {
+	trace := TraceInfo{
+		SignoffPeople: "李四",
+		Memo:          "请联系13912345678",
+		BizEmpName:    "王五",
+		BizEmpPhone:   "13700001111",
+	}
+
+	tests := []struct {
+		name   string
+		policy MaskPolicy
+		want   TraceInfo
+	}{
+		{
+			name:   "default",
+			policy: DefaultMaskPolicy,
+			want: TraceInfo{
+				SignoffPeople: "李*",
+				Memo:          "请联系139****5678",
+				BizEmpName:    "王*",
+				BizEmpPhone:   "137****1111",
+			},
+		},
+		{
+			name:   "keep courier info",
+			policy: MaskPolicy{PhoneKeepPrefix: 3, PhoneKeepSuffix: 4, NameKeepPrefix: 1, KeepCourierInfo: true, MaskChar: '#'},
+			want: TraceInfo{
+				SignoffPeople: "李#",
+				Memo:          "请联系139####5678",
+				BizEmpName:    "王五",
+				BizEmpPhone:   "13700001111",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trace.Masked(tt.policy); got != tt.want {
+				t.Errorf("Masked() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderContactMasked(t *testing.T) {
+	contact := OrderContact{
+		Name:     "张三丰",
+		Mobile:   "13800001234",
+		IDNo:     "11010119900307123X",
+		Province: "北京市",
+		Address:  "朝阳区建国路88号院3号楼",
+	}
+
+	tests := []struct {
+		name   string
+		policy MaskPolicy
+		want   OrderContact
+	}{
+		{
+			name:   "default",
+			policy: DefaultMaskPolicy,
+			want: OrderContact{
+				Name:     "张**",
+				Mobile:   "138****1234",
+				IDNo:     "110***********123X",
+				Province: "北京市",
+				Address:  "朝阳区建国路*******",
+			},
+		},
+		{
+			name:   "id number has its own policy",
+			policy: MaskPolicy{PhoneKeepPrefix: 3, PhoneKeepSuffix: 4, IDKeepPrefix: 1, IDKeepSuffix: 1},
+			want: OrderContact{
+				Name:     "***",
+				Mobile:   "138****1234",
+				IDNo:     "1****************X",
+				Province: "北京市",
+				Address:  "*************",
+			},
+		},
+		{
+			name:   "nothing kept",
+			policy: MaskPolicy{},
+			want: OrderContact{
+				Name:     "***",
+				Mobile:   "***********",
+				IDNo:     "******************",
+				Province: "北京市",
+				Address:  "*************",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contact.Masked(tt.policy); got != tt.want {
+				t.Errorf("Masked() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
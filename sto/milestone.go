@@ -0,0 +1,121 @@
+package sto
+
+// Milestone 标准物流节点，与常见电商平台的物流轨迹节点对应
+type Milestone string
+
+const (
+	MilestoneInfoReceived   Milestone = "info_received"    // 已下单，尚未揽收
+	MilestoneInTransit      Milestone = "in_transit"       // 运输中
+	MilestoneOutForDelivery Milestone = "out_for_delivery" // 派送中
+	MilestoneAwaitingPickup Milestone = "awaiting_pickup"  // 待取件，已放入快递柜，等待收件人取出
+	MilestoneDelivered      Milestone = "delivered"        // 已签收
+	MilestoneException      Milestone = "exception"        // 异常
+	MilestoneReturning      Milestone = "returning"        // 退回中，退回寄件人途中
+	MilestoneReturned       Milestone = "returned"         // 已退回，寄件人已签收退回件
+	MilestoneUnknown        Milestone = "unknown"          // 无法识别
+)
+
+// Milestones 全部标准节点，按物流流程排列
+var Milestones = []Milestone{
+	MilestoneInfoReceived,
+	MilestoneInTransit,
+	MilestoneOutForDelivery,
+	MilestoneAwaitingPickup,
+	MilestoneDelivered,
+	MilestoneException,
+	MilestoneReturning,
+	MilestoneReturned,
+}
+
+// ScanTypeMilestones 扫描类型与标准节点的对应关系
+var ScanTypeMilestones = map[string]Milestone{
+	"收件":    MilestoneInTransit,
+	"发件":    MilestoneInTransit,
+	"到件":    MilestoneInTransit,
+	"转寄":    MilestoneInTransit,
+	"派件":    MilestoneOutForDelivery,
+	"第三方代派": MilestoneOutForDelivery,
+	"签收":    MilestoneDelivered,
+	"派件入柜":  MilestoneAwaitingPickup,
+	"柜机代收":  MilestoneDelivered,
+	"驿站代收":  MilestoneDelivered,
+	"快件取出":  MilestoneDelivered,
+	"问题件":   MilestoneException,
+	"留仓件":   MilestoneException,
+	"退回件":   MilestoneReturning,
+}
+
+// IsTerminal 是否为终态节点（已签收，或退回后寄件人已签收）。
+// 退回中不是终态：退回件扫描后运单会重新进入运输、派送流程
+func (m Milestone) IsTerminal() bool {
+	return m == MilestoneDelivered || m == MilestoneReturned
+}
+
+// MilestoneOf 返回扫描类型对应的标准节点，未收录的扫描类型返回MilestoneUnknown
+func MilestoneOf(scanType string) Milestone {
+	if m, ok := ScanTypeMilestones[scanType]; ok {
+		return m
+	}
+	return MilestoneUnknown
+}
+
+// Milestone 返回轨迹对应的标准节点
+func (t TraceInfo) Milestone() Milestone {
+	return MilestoneOf(t.ScanType)
+}
+
+// CurrentMilestone 根据轨迹列表判断运单当前所处的标准节点。
+// 没有轨迹时返回MilestoneInfoReceived；发生过退回的运单在退回途中（运输、派送）返回MilestoneReturning，
+// 签收后返回MilestoneReturned
+func CurrentMilestone(traces []TraceInfo) Milestone {
+	latest := latestTrace(traces)
+	if latest == nil {
+		return MilestoneInfoReceived
+	}
+
+	m := latest.Milestone()
+	switch m {
+	case MilestoneInTransit, MilestoneOutForDelivery, MilestoneAwaitingPickup, MilestoneDelivered:
+		for _, t := range traces {
+			if t.Milestone() == MilestoneReturning {
+				if m == MilestoneDelivered {
+					return MilestoneReturned
+				}
+				return MilestoneReturning
+			}
+		}
+	}
+	return m
+}
+
+// latestTrace 返回操作时间最晚的一条轨迹，轨迹列表可以是任意顺序。
+// 操作时间只精确到秒，相同时取流程上更靠后的一条（见latestRank），仍相同时取列表中靠后的一条
+func latestTrace(traces []TraceInfo) *TraceInfo {
+	var latest *TraceInfo
+	for i := range traces {
+		if latest == nil {
+			latest = &traces[i]
+			continue
+		}
+		// 操作时间格式为yyyy-MM-dd HH:mm:ss，可以直接按字符串比较
+		switch t := &traces[i]; {
+		case t.OpTime > latest.OpTime:
+			latest = t
+		case t.OpTime == latest.OpTime && latestRank[t.Milestone()] >= latestRank[latest.Milestone()]:
+			latest = t
+		}
+	}
+	return latest
+}
+
+// latestRank 操作时间相同时判断哪条轨迹更晚的节点顺序，数值大的更晚，未收录的节点为0。
+// 退回件扫描后运单重新进入运输流程，与运输中同级；签收类节点最晚
+var latestRank = map[Milestone]int{
+	MilestoneInTransit:      1,
+	MilestoneReturning:      1,
+	MilestoneException:      2,
+	MilestoneOutForDelivery: 3,
+	MilestoneAwaitingPickup: 4,
+	MilestoneDelivered:      5,
+	MilestoneReturned:       5,
+}
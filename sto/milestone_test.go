@@ -0,0 +1,118 @@
+package sto_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestCurrentMilestone(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	gen := stotest.NewGenerator(42)
+	returned := gen.Traces(gen.WaybillNo(), stotest.ScenarioReturned, start)
+	returnScan := -1
+	for i, tr := range returned {
+		if tr.ScanType == "退回件" {
+			returnScan = i
+			break
+		}
+	}
+	if returnScan < 0 {
+		t.Fatal("returned scenario has no 退回件 scan")
+	}
+
+	tests := []struct {
+		name         string
+		traces       []sto.TraceInfo
+		want         sto.Milestone
+		wantTerminal bool
+	}{
+		{"no traces", nil, sto.MilestoneInfoReceived, false},
+		{"in transit", gen.Traces(gen.WaybillNo(), stotest.ScenarioInTransit, start), sto.MilestoneInTransit, false},
+		{"out for delivery", gen.Traces(gen.WaybillNo(), stotest.ScenarioOutForDelivery, start), sto.MilestoneOutForDelivery, false},
+		{"delivered", gen.Traces(gen.WaybillNo(), stotest.ScenarioDelivered, start), sto.MilestoneDelivered, true},
+		{"locker", gen.Traces(gen.WaybillNo(), stotest.ScenarioLocker, start), sto.MilestoneDelivered, true},
+		{"exception", gen.Traces(gen.WaybillNo(), stotest.ScenarioException, start), sto.MilestoneException, false},
+		{"delayed", gen.Traces(gen.WaybillNo(), stotest.ScenarioDelayed, start), sto.MilestoneDelivered, true},
+		{"return scanned", returned[:returnScan+1], sto.MilestoneReturning, false},
+		{"return leg in transit", returned[:returnScan+2], sto.MilestoneReturning, false},
+		{"return out for delivery", returned[:len(returned)-1], sto.MilestoneReturning, false},
+		{"returned and signed", returned, sto.MilestoneReturned, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sto.CurrentMilestone(tt.traces)
+			if got != tt.want {
+				t.Errorf("CurrentMilestone() = %s, want %s", got, tt.want)
+			}
+			if got.IsTerminal() != tt.wantTerminal {
+				t.Errorf("IsTerminal() = %v, want %v", got.IsTerminal(), tt.wantTerminal)
+			}
+		})
+	}
+}
+
+func TestCurrentMilestoneIgnoresOrder(t *testing.T) {
+	gen := stotest.NewGenerator(7)
+	traces := gen.Traces(gen.WaybillNo(), stotest.ScenarioReturned, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	reversed := make([]sto.TraceInfo, len(traces))
+	for i, tr := range traces {
+		reversed[len(traces)-1-i] = tr
+	}
+	if got := sto.CurrentMilestone(reversed); got != sto.MilestoneReturned {
+		t.Errorf("CurrentMilestone(desc) = %s, want %s", got, sto.MilestoneReturned)
+	}
+}
+
+func TestCurrentMilestoneSameSecond(t *testing.T) {
+	const at = "2024-03-01 09:00:00"
+	trace := func(scanType string) sto.TraceInfo {
+		return sto.TraceInfo{OpTime: at, ScanType: scanType}
+	}
+	tests := []struct {
+		name   string
+		traces []sto.TraceInfo
+		want   sto.Milestone
+	}{
+		{"locker then pickup", []sto.TraceInfo{trace("派件入柜"), trace("快件取出")}, sto.MilestoneDelivered},
+		{"pickup then locker", []sto.TraceInfo{trace("快件取出"), trace("派件入柜")}, sto.MilestoneDelivered},
+		{"dispatch then sign", []sto.TraceInfo{trace("派件"), trace("签收")}, sto.MilestoneDelivered},
+		{"sign then dispatch", []sto.TraceInfo{trace("签收"), trace("派件")}, sto.MilestoneDelivered},
+		{"dispatch then locker", []sto.TraceInfo{trace("派件"), trace("派件入柜")}, sto.MilestoneAwaitingPickup},
+		{"locker then dispatch", []sto.TraceInfo{trace("派件入柜"), trace("派件")}, sto.MilestoneAwaitingPickup},
+		{"exception then dispatch", []sto.TraceInfo{trace("问题件"), trace("派件")}, sto.MilestoneOutForDelivery},
+		{"same milestone uses list order", []sto.TraceInfo{trace("派件"), trace("第三方代派")}, sto.MilestoneOutForDelivery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sto.CurrentMilestone(tt.traces); got != tt.want {
+				t.Errorf("CurrentMilestone() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMilestoneOfLocker(t *testing.T) {
+	if got := sto.MilestoneOf("派件入柜"); got != sto.MilestoneAwaitingPickup {
+		t.Errorf("MilestoneOf(派件入柜) = %s, want %s", got, sto.MilestoneAwaitingPickup)
+	}
+	if sto.MilestoneAwaitingPickup.IsTerminal() {
+		t.Error("awaiting pickup must not be terminal")
+	}
+
+	gen := stotest.NewGenerator(3)
+	traces := gen.Traces(gen.WaybillNo(), stotest.ScenarioLocker, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	for i, tr := range traces {
+		if tr.ScanType == "派件入柜" {
+			if got := sto.CurrentMilestone(traces[:i+1]); got != sto.MilestoneAwaitingPickup {
+				t.Errorf("CurrentMilestone(in locker) = %s, want %s", got, sto.MilestoneAwaitingPickup)
+			}
+			return
+		}
+	}
+	t.Fatal("locker scenario has no 派件入柜 scan")
+}
@@ -0,0 +1,101 @@
+package sto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoAuthorizedAccount 所有账号都未查到运单轨迹
+var ErrNoAuthorizedAccount = errors.New("no account returned traces for the waybill")
+
+// AccountTraceResult 单个账号的查询结果
+type AccountTraceResult struct {
+	AppKey   string      // 账号的APP KEY
+	FromCode string      // 账号的商户编码
+	Traces   []TraceInfo // 查到的轨迹
+	Err      error       // 查询失败、无权限或未查到轨迹的原因；因其他账号已命中而取消时为context.Canceled
+}
+
+// MultiAccountTraceResult 多账号查询结果
+type MultiAccountTraceResult struct {
+	WaybillNo string               // 运单号
+	Client    *Client              // 查到轨迹的账号，未查到时为nil
+	Traces    []TraceInfo          // 查到的轨迹
+	Accounts  []AccountTraceResult // 各账号的查询结果，与传入的clients顺序一致
+}
+
+// QueryTraceAcrossAccounts 在运单所属账号未知时，并发使用多个账号查询轨迹，返回第一个查到轨迹的账号结果，
+// 其余查询随即取消。所有账号都未查到时返回ErrNoAuthorizedAccount，Accounts中包含各账号的错误详情；
+// 未查到前ctx已结束时返回ctx.Err()
+func QueryTraceAcrossAccounts(parent context.Context, clients []*Client, waybillNo string) (*MultiAccountTraceResult, error) {
+	if len(clients) == 0 {
+		return nil, &InvalidRequestError{Err: fmt.Errorf("clients cannot be empty")}
+	}
+	if waybillNo == "" {
+		return nil, &InvalidRequestError{Err: fmt.Errorf("waybillNo cannot be empty")}
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	result := &MultiAccountTraceResult{
+		WaybillNo: waybillNo,
+		Accounts:  make([]AccountTraceResult, len(clients)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+
+			r := AccountTraceResult{AppKey: c.AppKey, FromCode: c.FromCode}
+			r.Traces, r.Err = queryAccountTraces(ctx, c, waybillNo)
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Accounts[i] = r
+			if r.Err == nil && result.Client == nil {
+				result.Client = c
+				result.Traces = r.Traces
+				cancel()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	if result.Client == nil {
+		// 调用方取消或超时时各账号的错误都是ctx错误，不能据此判断运单不属于这些账号
+		if err := parent.Err(); err != nil {
+			return result, err
+		}
+		return result, ErrNoAuthorizedAccount
+	}
+	return result, nil
+}
+
+// queryAccountTraces 使用单个账号查询轨迹，未查到轨迹时返回错误
+func queryAccountTraces(ctx context.Context, c *Client, waybillNo string) ([]TraceInfo, error) {
+	resp, err := c.QueryTraceContext(ctx, &TraceQueryRequest{
+		Order:         "asc",
+		WaybillNoList: []string{waybillNo},
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+
+	traces := resp.Data[waybillNo]
+	if len(traces) == 0 {
+		return nil, fmt.Errorf("no traces for waybill %s", waybillNo)
+	}
+	return traces, nil
+}
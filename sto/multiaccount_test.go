@@ -0,0 +1,86 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestQueryTraceAcrossAccounts(t *testing.T) {
+	const no = "773000000001"
+	owned := func(json.RawMessage) (interface{}, error) {
+		return map[string][]sto.TraceInfo{no: {{WaybillNo: no, ScanType: "收件"}}}, nil
+	}
+	notOwned := func(json.RawMessage) (interface{}, error) {
+		return map[string][]sto.TraceInfo{no: {}}, nil
+	}
+	slow := func(json.RawMessage) (interface{}, error) {
+		time.Sleep(200 * time.Millisecond)
+		return map[string][]sto.TraceInfo{no: {}}, nil
+	}
+
+	tests := []struct {
+		name       string
+		handlers   []stotest.HandlerFunc
+		timeout    time.Duration
+		wantErr    error
+		wantClient int // 命中账号的下标，-1表示未命中
+	}{
+		{
+			name:       "second account owns waybill",
+			handlers:   []stotest.HandlerFunc{notOwned, owned},
+			wantClient: 1,
+		},
+		{
+			name:       "no account owns waybill",
+			handlers:   []stotest.HandlerFunc{notOwned, notOwned},
+			wantErr:    sto.ErrNoAuthorizedAccount,
+			wantClient: -1,
+		},
+		{
+			name:       "parent context expires",
+			handlers:   []stotest.HandlerFunc{slow, slow},
+			timeout:    20 * time.Millisecond,
+			wantErr:    context.DeadlineExceeded,
+			wantClient: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clients []*sto.Client
+			for _, h := range tt.handlers {
+				gw := stotest.NewGateway(testAppSecret)
+				gw.Handle("STO_TRACE_QUERY_COMMON", h)
+				clients = append(clients, newTestClient(t, gw, sto.WithMaxRetries(0)))
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			result, err := sto.QueryTraceAcrossAccounts(ctx, clients, no)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var want *sto.Client
+			if tt.wantClient >= 0 {
+				want = clients[tt.wantClient]
+			}
+			if result.Client != want {
+				t.Errorf("Client = %p, want %p", result.Client, want)
+			}
+			if len(result.Accounts) != len(clients) {
+				t.Errorf("got %d account results, want %d", len(result.Accounts), len(clients))
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package sto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// orderQueryEndpoint 订单查询接口
+var orderQueryEndpoint = endpoint{
+	apiName:  "OMS_EXPRESS_ORDER_QUERY",
+	toAppKey: "sto_oms",
+	toCode:   "sto_oms",
+}
+
+// orderCreateEndpoint 下单接口
+var orderCreateEndpoint = endpoint{
+	apiName:  "OMS_EXPRESS_ORDER_CREATE",
+	toAppKey: "sto_oms",
+	toCode:   "sto_oms",
+
+	emptyFields:   emptyFieldsExplicit,
+	encryptFields: true,
+}
+
+// orderCancelEndpoint 取消订单接口
+var orderCancelEndpoint = endpoint{
+	apiName:  "OMS_EXPRESS_ORDER_CANCEL",
+	toAppKey: "sto_oms",
+	toCode:   "sto_oms",
+}
+
+// OrderContact 寄/收件人信息
+type OrderContact struct {
+	Name     string `json:"name"`                         // 姓名
+	Mobile   string `json:"mobile" sto:"encrypt"`         // 手机号码，开启字段加密时加密传输
+	Tel      string `json:"tel" sto:"encrypt"`            // 固定电话，开启字段加密时加密传输
+	IDNo     string `json:"idNo,omitempty" sto:"encrypt"` // 证件号码，实名寄递时填写寄件人的证件号，开启字段加密时加密传输
+	Province string `json:"province"`                     // 省
+	City     string `json:"city"`                         // 市
+	Area     string `json:"area"`                         // 区县
+	Address  string `json:"address"`                      // 详细地址
+}
+
+// OrderCargo 货物信息
+type OrderCargo struct {
+	Name     string `json:"name"`     // 物品名称
+	Weight   string `json:"weight"`   // 重量，单位：kg
+	Quantity int    `json:"quantity"` // 件数
+}
+
+// OrderCreateRequest 下单请求参数
+type OrderCreateRequest struct {
+	OrderNo  string       `json:"orderNo"`  // 商家订单号
+	Sender   OrderContact `json:"sender"`   // 寄件人
+	Receiver OrderContact `json:"receiver"` // 收件人
+	Cargo    OrderCargo   `json:"cargo"`    // 货物信息
+	CodValue Amount       `json:"codValue"` // 代收货款金额，0表示不代收
+	Remark   string       `json:"remark"`   // 备注
+}
+
+// Issues 校验下单参数，返回全部问题
+func (r *OrderCreateRequest) Issues() []ValidationIssue {
+	var issues []ValidationIssue
+	if r.OrderNo == "" {
+		issues = append(issues, ValidationIssue{Field: "orderNo", Message: "cannot be empty"})
+	}
+	issues = append(issues, r.Sender.Issues("sender")...)
+	issues = append(issues, r.Receiver.Issues("receiver")...)
+	if r.Cargo.Quantity < 0 {
+		issues = append(issues, ValidationIssue{Field: "cargo.quantity", Message: "cannot be negative"})
+	}
+	if r.CodValue.Cmp(Amount{}) < 0 {
+		issues = append(issues, ValidationIssue{Field: "codValue", Message: "cannot be negative"})
+	}
+	return issues
+}
+
+// Validate 验证请求参数，失败时返回*ValidationError
+func (r *OrderCreateRequest) Validate() error {
+	return issuesError(r.Issues())
+}
+
+// OrderCreateResult 下单结果
+type OrderCreateResult struct {
+	OrderNo   string `json:"orderNo"`   // 商家订单号
+	WaybillNo string `json:"waybillNo"` // 分配的运单号
+	BigChar   string `json:"bigChar"`   // 大头笔
+}
+
+// OrderCreateResponse 下单响应
+type OrderCreateResponse struct {
+	BaseResponse
+	Data *OrderCreateResult `json:"data"` // 下单结果
+}
+
+// CreateOrder 下单并获取运单号
+func (c *Client) CreateOrder(ctx context.Context, req *OrderCreateRequest) (*OrderCreateResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, orderCreateEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &OrderCreateResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
+
+// OrderCancelRequest 取消订单请求参数
+type OrderCancelRequest struct {
+	OrderNo   string `json:"orderNo"`   // 商家订单号
+	WaybillNo string `json:"waybillNo"` // 运单号，已分配时填写
+	Reason    string `json:"reason"`    // 取消原因
+}
+
+// Validate 验证请求参数
+func (r *OrderCancelRequest) Validate() error {
+	if r.OrderNo == "" {
+		return fmt.Errorf("orderNo cannot be empty")
+	}
+	return nil
+}
+
+// OrderCancelResponse 取消订单响应
+type OrderCancelResponse struct {
+	BaseResponse
+}
+
+// CancelOrder 取消尚未揽收的订单
+func (c *Client) CancelOrder(ctx context.Context, req *OrderCancelRequest) (*OrderCancelResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, orderCancelEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	return &OrderCancelResponse{BaseResponse: res.resp.BaseResponse}, err
+}
+
+// OrderQueryRequest 订单查询请求参数
+type OrderQueryRequest struct {
+	OrderNo string `json:"orderNo"` // 订单号
+}
+
+// Validate 验证请求参数
+func (r *OrderQueryRequest) Validate() error {
+	if r.OrderNo == "" {
+		return fmt.Errorf("orderNo cannot be empty")
+	}
+	return nil
+}
+
+// OrderInfo 订单信息
+type OrderInfo struct {
+	OrderNo         string       `json:"orderNo"`         // 订单号
+	WaybillNo       string       `json:"waybillNo"`       // 运单号，未分配时为空
+	OrderStatus     string       `json:"orderStatus"`     // 订单状态
+	OrderStatusDesc string       `json:"orderStatusDesc"` // 订单状态描述
+	CreateTime      string       `json:"createTime"`      // 下单时间
+	Sender          OrderContact `json:"sender"`          // 寄件人
+	Receiver        OrderContact `json:"receiver"`        // 收件人
+	Weight          string       `json:"weight"`          // 重量
+	CodValue        Amount       `json:"codValue"`        // 代收货款金额
+}
+
+// OrderQueryResponse 订单查询响应
+type OrderQueryResponse struct {
+	BaseResponse
+	Data *OrderInfo `json:"data"` // 订单信息
+}
+
+// QueryOrder 查询订单状态及对应的运单号
+func (c *Client) QueryOrder(ctx context.Context, req *OrderQueryRequest) (*OrderQueryResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, orderQueryEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &OrderQueryResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
+
+// ErrOrderNotFound 网关未返回订单信息，订单号不存在或不属于当前账号
+var ErrOrderNotFound = errors.New("order not found")
+
+// ShipmentStatus 订单的发货状态：订单信息、运单号及物流轨迹
+type ShipmentStatus struct {
+	OrderNo     string      // 订单号
+	Order       *OrderInfo  // 订单信息
+	WaybillNo   string      // 运单号，订单尚未分配运单号时为空
+	Traces      []TraceInfo // 物流轨迹，按时间升序排列
+	LatestTrace *TraceInfo  // 最新一条轨迹，没有轨迹时为nil
+}
+
+// GetShipmentStatusByOrderNo 依次查询订单、解析运单号并查询物流轨迹，返回汇总结果。
+// 订单尚未分配运单号时返回的WaybillNo和Traces为空；订单不存在时返回包装了ErrOrderNotFound的错误
+func (c *Client) GetShipmentStatusByOrderNo(ctx context.Context, orderNo string) (*ShipmentStatus, error) {
+	orderResp, err := c.QueryOrder(ctx, &OrderQueryRequest{OrderNo: orderNo})
+	if err != nil {
+		return nil, err
+	}
+	if err := orderResp.Err(); err != nil {
+		return nil, err
+	}
+	if orderResp.Data == nil {
+		return nil, fmt.Errorf("order %s: %w", orderNo, ErrOrderNotFound)
+	}
+
+	status := &ShipmentStatus{
+		OrderNo:   orderNo,
+		Order:     orderResp.Data,
+		WaybillNo: orderResp.Data.WaybillNo,
+	}
+	if status.WaybillNo == "" {
+		return status, nil
+	}
+
+	traceResp, err := c.QueryTraceContext(ctx, &TraceQueryRequest{
+		Order:         "asc",
+		WaybillNoList: []string{status.WaybillNo},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := traceResp.Err(); err != nil {
+		return nil, err
+	}
+
+	status.Traces = traceResp.Data[status.WaybillNo]
+	if n := len(status.Traces); n > 0 {
+		status.LatestTrace = &status.Traces[n-1]
+	}
+	return status, nil
+}
@@ -0,0 +1,99 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestGetShipmentStatusByOrderNo(t *testing.T) {
+	const (
+		orderNo   = "ORDER-1"
+		waybillNo = "773000000001"
+	)
+	start := time.Now().Add(-48 * time.Hour)
+	traces := stotest.NewGenerator(1).Traces(waybillNo, stotest.ScenarioDelivered, start)
+
+	tests := []struct {
+		name          string
+		order         *sto.OrderInfo
+		wantErr       error
+		wantWaybillNo string
+		wantTraces    int
+	}{
+		{
+			name:    "order not found",
+			wantErr: sto.ErrOrderNotFound,
+		},
+		{
+			name:  "no waybill yet",
+			order: &sto.OrderInfo{OrderNo: orderNo, OrderStatus: "0"},
+		},
+		{
+			name:          "waybill with traces",
+			order:         &sto.OrderInfo{OrderNo: orderNo, WaybillNo: waybillNo},
+			wantWaybillNo: waybillNo,
+			wantTraces:    len(traces),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var traceQueries int
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("OMS_EXPRESS_ORDER_QUERY", func(content json.RawMessage) (interface{}, error) {
+				var req sto.OrderQueryRequest
+				if err := json.Unmarshal(content, &req); err != nil {
+					return nil, err
+				}
+				if req.OrderNo != orderNo {
+					t.Errorf("queried order %q, want %q", req.OrderNo, orderNo)
+				}
+				return tt.order, nil
+			})
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+				traceQueries++
+				return map[string][]sto.TraceInfo{waybillNo: traces}, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+			status, err := client.GetShipmentStatusByOrderNo(context.Background(), orderNo)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if status.OrderNo != orderNo || status.Order == nil {
+				t.Errorf("status = %+v, want order %s", status, orderNo)
+			}
+			if status.WaybillNo != tt.wantWaybillNo {
+				t.Errorf("WaybillNo = %q, want %q", status.WaybillNo, tt.wantWaybillNo)
+			}
+			if len(status.Traces) != tt.wantTraces {
+				t.Errorf("len(Traces) = %d, want %d", len(status.Traces), tt.wantTraces)
+			}
+			if tt.wantWaybillNo == "" {
+				if traceQueries != 0 {
+					t.Errorf("trace queries = %d, want none before a waybill is assigned", traceQueries)
+				}
+				if status.LatestTrace != nil {
+					t.Errorf("LatestTrace = %+v, want nil", status.LatestTrace)
+				}
+				return
+			}
+			if status.LatestTrace == nil || *status.LatestTrace != traces[len(traces)-1] {
+				t.Errorf("LatestTrace = %+v, want %+v", status.LatestTrace, traces[len(traces)-1])
+			}
+		})
+	}
+}
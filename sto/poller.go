@@ -0,0 +1,215 @@
+package sto
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPollInterval 默认轮询间隔
+	DefaultPollInterval = 5 * time.Minute
+
+	// DefaultPollBatchSize 默认每次查询的运单数
+	DefaultPollBatchSize = 20
+)
+
+// PollerConfig 轨迹轮询配置，零值字段使用默认值
+type PollerConfig struct {
+	Interval    time.Duration // 正常情况下的轮询间隔，也是最小间隔
+	MaxInterval time.Duration // 退避后的最大间隔，默认为Interval的16倍
+	BatchSize   int           // 每次查询的运单数
+
+	// ErrorRateThreshold 一轮轮询中失败（含限流）比例超过该值时退避，默认0.2
+	ErrorRateThreshold float64
+	// BackoffFactor 退避时间隔乘以该系数，默认2
+	BackoffFactor float64
+	// RecoveryStep 恢复时每轮缩短的间隔，默认为Interval的1/4
+	RecoveryStep time.Duration
+
+	// OnTraces 运单有新轨迹时调用，traces为该运单的完整轨迹
+	OnTraces func(waybillNo string, traces []TraceInfo)
+	// OnError 查询失败时调用
+	OnError func(err error)
+}
+
+// Poller 定时轮询运单轨迹。网关错误率或限流升高时成倍拉长轮询间隔，
+// 恢复后逐步缩短（AIMD），避免在网关降级时持续高频请求。运单签收（含退回后寄件人签收）后自动停止轮询，
+// 退回途中继续轮询
+type Poller struct {
+	client *Client
+	cfg    PollerConfig
+
+	mu       sync.Mutex
+	waybills map[string]int // 运单号 -> 已通知的轨迹条数
+	interval time.Duration
+}
+
+// NewPoller 创建轨迹轮询器
+func NewPoller(client *Client, cfg PollerConfig) *Poller {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultPollInterval
+	}
+	if cfg.MaxInterval < cfg.Interval {
+		cfg.MaxInterval = cfg.Interval * 16
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultPollBatchSize
+	}
+	if cfg.ErrorRateThreshold <= 0 {
+		cfg.ErrorRateThreshold = 0.2
+	}
+	if cfg.BackoffFactor <= 1 {
+		cfg.BackoffFactor = 2
+	}
+	if cfg.RecoveryStep <= 0 {
+		cfg.RecoveryStep = cfg.Interval / 4
+	}
+
+	return &Poller{
+		client:   client,
+		cfg:      cfg,
+		waybills: make(map[string]int),
+		interval: cfg.Interval,
+	}
+}
+
+// Add 添加需要轮询的运单
+func (p *Poller) Add(waybillNos ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, no := range waybillNos {
+		if _, ok := p.waybills[no]; !ok {
+			p.waybills[no] = 0
+		}
+	}
+}
+
+// Remove 停止轮询运单
+func (p *Poller) Remove(waybillNos ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, no := range waybillNos {
+		delete(p.waybills, no)
+	}
+}
+
+// Len 返回正在轮询的运单数
+func (p *Poller) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.waybills)
+}
+
+// Interval 返回当前的轮询间隔
+func (p *Poller) Interval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.interval
+}
+
+// Run 开始轮询，直到ctx结束
+func (p *Poller) Run(ctx context.Context) {
+	for {
+		p.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.Interval()):
+		}
+	}
+}
+
+// Poll 执行一轮轮询，并根据本轮的错误率调整轮询间隔
+func (p *Poller) Poll(ctx context.Context) {
+	p.mu.Lock()
+	waybillNos := make([]string, 0, len(p.waybills))
+	for no := range p.waybills {
+		waybillNos = append(waybillNos, no)
+	}
+	p.mu.Unlock()
+	sort.Strings(waybillNos)
+
+	batches, failures := 0, 0
+	for start := 0; start < len(waybillNos); start += p.cfg.BatchSize {
+		if ctx.Err() != nil {
+			return
+		}
+		end := start + p.cfg.BatchSize
+		if end > len(waybillNos) {
+			end = len(waybillNos)
+		}
+
+		batches++
+		if !p.pollBatch(ctx, waybillNos[start:end]) {
+			failures++
+		}
+	}
+
+	if batches > 0 {
+		p.adjust(float64(failures) / float64(batches))
+	}
+}
+
+// pollBatch 查询一批运单，返回网关是否正常响应
+func (p *Poller) pollBatch(ctx context.Context, batch []string) bool {
+	resp, err := p.client.QueryTraceContext(ctx, &TraceQueryRequest{
+		Order:         "asc",
+		WaybillNoList: batch,
+	})
+	if err == nil {
+		err = resp.Err()
+	}
+	if err != nil {
+		if p.cfg.OnError != nil {
+			p.cfg.OnError(err)
+		}
+		return !isGatewayPressure(err)
+	}
+	if resp.Stale {
+		// 降级返回的缓存数据没有新轨迹，按网关异常处理
+		return false
+	}
+
+	for _, no := range batch {
+		traces := resp.Data[no]
+
+		p.mu.Lock()
+		seen, ok := p.waybills[no]
+		changed := ok && len(traces) > seen
+		if changed {
+			p.waybills[no] = len(traces)
+		}
+		// 只有最新一条轨迹为签收时才停止，退回件扫描后运单仍会继续运输和派送
+		if ok && CurrentMilestone(traces).IsTerminal() {
+			delete(p.waybills, no)
+		}
+		p.mu.Unlock()
+
+		if changed && p.cfg.OnTraces != nil {
+			p.cfg.OnTraces(no, traces)
+		}
+	}
+	return true
+}
+
+// adjust 按AIMD调整轮询间隔：错误率超过阈值时乘以退避系数，否则减去恢复步长
+func (p *Poller) adjust(errorRate float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if errorRate > p.cfg.ErrorRateThreshold {
+		p.interval = time.Duration(float64(p.interval) * p.cfg.BackoffFactor)
+		if p.interval > p.cfg.MaxInterval {
+			p.interval = p.cfg.MaxInterval
+		}
+		return
+	}
+
+	p.interval -= p.cfg.RecoveryStep
+	if p.interval < p.cfg.Interval {
+		p.interval = p.cfg.Interval
+	}
+}
@@ -0,0 +1,101 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestPollerAIMD(t *testing.T) {
+	const interval = 100 * time.Millisecond
+
+	tests := []struct {
+		name   string
+		rounds []bool // 每轮网关是否正常
+		want   []time.Duration
+	}{
+		{
+			name:   "healthy stays at interval",
+			rounds: []bool{true, true},
+			want:   []time.Duration{interval, interval},
+		},
+		{
+			name:   "backoff is multiplicative and capped",
+			rounds: []bool{false, false, false, false},
+			want:   []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 800 * time.Millisecond},
+		},
+		{
+			name:   "recovery is additive",
+			rounds: []bool{false, false, true, true, true, true, true, true, true},
+			want: []time.Duration{
+				200 * time.Millisecond, 400 * time.Millisecond,
+				375 * time.Millisecond, 350 * time.Millisecond, 325 * time.Millisecond, 300 * time.Millisecond,
+				275 * time.Millisecond, 250 * time.Millisecond, 225 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy := true
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_TRACE_QUERY_COMMON", func(json.RawMessage) (interface{}, error) {
+				if !healthy {
+					return nil, &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, ErrorMsg: "系统繁忙"}
+				}
+				return map[string][]sto.TraceInfo{}, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0))
+			p := sto.NewPoller(client, sto.PollerConfig{
+				Interval:     interval,
+				MaxInterval:  8 * interval,
+				RecoveryStep: 25 * time.Millisecond,
+			})
+			p.Add("773000000001")
+
+			for i, ok := range tt.rounds {
+				healthy = ok
+				p.Poll(context.Background())
+				if got := p.Interval(); got != tt.want[i] {
+					t.Errorf("round %d: interval = %s, want %s", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPollerStopsOnlyAfterSignoff(t *testing.T) {
+	gen := stotest.NewGenerator(3)
+	no := gen.WaybillNo()
+	traces := gen.Traces(no, stotest.ScenarioReturned, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	gw := stotest.NewGateway(testAppSecret)
+	gw.SetTraces(no, traces)
+	client := newTestClient(t, gw)
+
+	var notified int
+	p := sto.NewPoller(client, sto.PollerConfig{
+		OnTraces: func(string, []sto.TraceInfo) { notified++ },
+	})
+	p.Add(no)
+
+	for i, tr := range traces {
+		at, err := tr.Time()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gw.SetClock(func() time.Time { return at })
+		p.Poll(context.Background())
+
+		last := i == len(traces)-1
+		if tracking := p.Len() == 1; tracking == last {
+			t.Fatalf("after %s scan %d/%d: tracking = %v", tr.ScanType, i+1, len(traces), tracking)
+		}
+	}
+	if notified != len(traces) {
+		t.Errorf("OnTraces called %d times, want %d", notified, len(traces))
+	}
+}
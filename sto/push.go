@@ -0,0 +1,132 @@
+package sto
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TracePush 申通推送的轨迹事件
+type TracePush struct {
+	WaybillNo string    `json:"waybillNo"` // 运单号
+	Trace     TraceInfo `json:"trace"`     // 轨迹信息
+}
+
+// PushHandleFunc 处理推送事件，返回错误时申通会重新推送
+type PushHandleFunc func(ctx context.Context, event *TracePush) error
+
+// PushOption 定义推送处理器选项
+type PushOption func(*PushHandler)
+
+// WithPushDispatcher 设置异步分发器，推送事件写入分发器队列后立即应答，
+// 避免下游处理慢导致申通推送超时和重复推送
+func WithPushDispatcher(d *PushDispatcher) PushOption {
+	return func(h *PushHandler) {
+		h.dispatcher = d
+	}
+}
+
+// WithPushLogger 设置推送处理器的日志输出
+func WithPushLogger(logger Logger) PushOption {
+	return func(h *PushHandler) {
+		h.logger = logger
+	}
+}
+
+// PushHandler 接收申通轨迹推送的http.Handler，校验data_digest后交给处理函数或分发器
+type PushHandler struct {
+	appSecret  string
+	handle     PushHandleFunc
+	dispatcher *PushDispatcher
+	logger     Logger
+}
+
+// NewPushHandler 创建推送处理器。设置WithPushDispatcher时推送只交给分发器，handle不会被调用，可以为nil；
+// 两者都未设置时panic，与http.Handle对nil处理函数的处理一致
+func NewPushHandler(appSecret string, handle PushHandleFunc, opts ...PushOption) *PushHandler {
+	h := &PushHandler{
+		appSecret: appSecret,
+		handle:    handle,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.handle == nil && h.dispatcher == nil {
+		panic("sto: NewPushHandler requires a handle func or WithPushDispatcher")
+	}
+	if h.logger == nil {
+		h.logger = stdoutLogger{}
+	}
+	return h
+}
+
+// pushReply 推送应答
+type pushReply struct {
+	Success   string `json:"success"`
+	ErrorCode string `json:"errorCode"`
+	ErrorMsg  string `json:"errorMsg"`
+	NeedRetry string `json:"needRetry"`
+}
+
+// ServeHTTP 实现http.Handler接口
+func (h *PushHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.reply(w, http.StatusBadRequest, PushErrorCodeInvalidForm, "invalid form", false)
+		return
+	}
+
+	content := r.Form.Get("content")
+	if !h.verify(content, r.Form.Get("data_digest")) {
+		h.reply(w, http.StatusUnauthorized, PushErrorCodeInvalidDigest, "invalid data_digest", false)
+		return
+	}
+
+	var event TracePush
+	if err := json.Unmarshal([]byte(content), &event); err != nil {
+		h.reply(w, http.StatusBadRequest, PushErrorCodeInvalidBody, "invalid content", false)
+		return
+	}
+	if event.WaybillNo == "" {
+		event.WaybillNo = event.Trace.WaybillNo
+	}
+
+	if h.dispatcher != nil {
+		if !h.dispatcher.Enqueue(&event) {
+			h.reply(w, http.StatusOK, PushErrorCodeQueueFull, "queue full", true)
+			return
+		}
+		h.reply(w, http.StatusOK, "", "", false)
+		return
+	}
+
+	if err := h.handle(r.Context(), &event); err != nil {
+		h.logger.Printf("handle trace push failed: waybillNo=%s err=%v", event.WaybillNo, err)
+		h.reply(w, http.StatusOK, PushErrorCodeHandleFailed, "handle failed", true)
+		return
+	}
+	h.reply(w, http.StatusOK, "", "", false)
+}
+
+// verify 校验data_digest
+func (h *PushHandler) verify(content, dataDigest string) bool {
+	sum := md5.Sum([]byte(content + h.appSecret))
+	expected := base64.StdEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(dataDigest)) == 1
+}
+
+// reply 写入推送应答
+func (h *PushHandler) reply(w http.ResponseWriter, status int, errorCode, errorMsg string, needRetry bool) {
+	reply := pushReply{
+		Success:   fmt.Sprint(errorCode == ""),
+		ErrorCode: errorCode,
+		ErrorMsg:  errorMsg,
+		NeedRetry: fmt.Sprint(needRetry),
+	}
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(reply)
+}
@@ -0,0 +1,190 @@
+package sto_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+// pushRequest 构造带签名的推送请求
+func pushRequest(content, secret string) *http.Request {
+	sum := md5.Sum([]byte(content + secret))
+	form := url.Values{}
+	form.Set("content", content)
+	form.Set("data_digest", base64.StdEncoding.EncodeToString(sum[:]))
+	r := httptest.NewRequest(http.MethodPost, "/sto/push", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestPushHandler(t *testing.T) {
+	const event = `{"waybillNo":"773000000001","trace":{"scanType":"签收"}}`
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		handleErr  error
+		wantStatus int
+		wantCode   string
+		wantRetry  string
+	}{
+		{"ok", pushRequest(event, testAppSecret), nil, http.StatusOK, "", "false"},
+		{"bad digest", pushRequest(event, "other"), nil, http.StatusUnauthorized, sto.PushErrorCodeInvalidDigest, "false"},
+		{"bad content", pushRequest("{", testAppSecret), nil, http.StatusBadRequest, sto.PushErrorCodeInvalidBody, "false"},
+		{"handle failed", pushRequest(event, testAppSecret), errors.New("db down"), http.StatusOK, sto.PushErrorCodeHandleFailed, "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := sto.NewPushHandler(testAppSecret, func(ctx context.Context, e *sto.TracePush) error {
+				if e.WaybillNo != "773000000001" {
+					t.Errorf("WaybillNo = %q", e.WaybillNo)
+				}
+				return tt.handleErr
+			}, sto.WithPushLogger(discardLogger{}))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var reply struct {
+				ErrorCode string `json:"errorCode"`
+				NeedRetry string `json:"needRetry"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+				t.Fatal(err)
+			}
+			if reply.ErrorCode != tt.wantCode || reply.NeedRetry != tt.wantRetry {
+				t.Errorf("reply = %+v, want code %q needRetry %s", reply, tt.wantCode, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestNewPushHandlerRejectsNilHandle(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewPushHandler(nil) without dispatcher did not panic")
+		}
+	}()
+	sto.NewPushHandler(testAppSecret, nil)
+}
+
+func TestPushDispatcherMaxRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantCalls  int64
+	}{
+		{"no retries", -1, 1},
+		{"default", 0, int64(sto.DefaultMaxRetries) + 1},
+		{"two retries", 2, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int64
+			dead := make(chan error, 1)
+			d := sto.NewPushDispatcher(func(ctx context.Context, e *sto.TracePush) error {
+				atomic.AddInt64(&calls, 1)
+				return errors.New("failed")
+			}, sto.PushDispatcherConfig{
+				Workers:      1,
+				MaxRetries:   tt.maxRetries,
+				RetryBackoff: time.Millisecond,
+				DeadLetter:   func(e *sto.TracePush, err error) { dead <- err },
+			})
+			if !d.Enqueue(&sto.TracePush{WaybillNo: "773000000001"}) {
+				t.Fatal("Enqueue failed")
+			}
+			if err := d.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-dead:
+			default:
+				t.Error("DeadLetter not called")
+			}
+			if got := atomic.LoadInt64(&calls); got != tt.wantCalls {
+				t.Errorf("handle called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPushDispatcherCloseDoesNotWaitAfterDeadline(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	d := sto.NewPushDispatcher(func(ctx context.Context, e *sto.TracePush) error {
+		close(started)
+		<-release // 模拟不响应ctx取消的下游
+		return nil
+	}, sto.PushDispatcherConfig{Workers: 1})
+	if !d.Enqueue(&sto.TracePush{WaybillNo: "773000000001"}) {
+		t.Fatal("Enqueue failed")
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := d.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close took %s, want it to return at the deadline", elapsed)
+	}
+	if d.Enqueue(&sto.TracePush{WaybillNo: "773000000002"}) {
+		t.Error("Enqueue after Close succeeded")
+	}
+}
+
+func TestPushHandlerPrefersDispatcher(t *testing.T) {
+	var direct int64
+	queued := make(chan string, 1)
+	d := sto.NewPushDispatcher(func(ctx context.Context, e *sto.TracePush) error {
+		queued <- e.WaybillNo
+		return nil
+	}, sto.PushDispatcherConfig{Workers: 1})
+	defer d.Close(context.Background())
+
+	h := sto.NewPushHandler(testAppSecret, func(ctx context.Context, e *sto.TracePush) error {
+		atomic.AddInt64(&direct, 1)
+		return nil
+	}, sto.WithPushDispatcher(d), sto.WithPushLogger(discardLogger{}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, pushRequest(`{"waybillNo":"773000000001"}`, testAppSecret))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	select {
+	case no := <-queued:
+		if no != "773000000001" {
+			t.Errorf("dispatched %q", no)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not dispatched")
+	}
+	if n := atomic.LoadInt64(&direct); n != 0 {
+		t.Errorf("handle called %d times, want 0 when a dispatcher is set", n)
+	}
+}
+
+// discardLogger 丢弃日志
+type discardLogger struct{}
+
+func (discardLogger) Printf(string, ...interface{}) {}
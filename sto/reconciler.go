@@ -0,0 +1,302 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReconcileInterval 默认对账间隔
+	DefaultReconcileInterval = time.Hour
+
+	// DefaultMaxSubscribeRejections 默认允许运单连续被申通逐单拒绝订阅或取消订阅的次数
+	DefaultMaxSubscribeRejections = 5
+)
+
+// ReconcilerConfig 订阅对账配置，零值字段使用默认值
+type ReconcilerConfig struct {
+	Interval  time.Duration // 对账间隔
+	BatchSize int           // 每批核对的运单数，不超过100
+
+	// MaxRejections 运单的订阅或取消订阅连续被申通逐单拒绝达到该次数后停止跟踪，并记入ReconcileReport.Rejected，
+	// 避免运单号已作废等永久性错误每轮都重试。整批调用失败不计入
+	MaxRejections int
+
+	// OnReport 每轮对账结束后调用
+	OnReport func(report *ReconcileReport)
+	// OnError 调用申通接口失败时调用
+	OnError func(err error)
+}
+
+// ReconcileReport 一轮对账的结果
+type ReconcileReport struct {
+	Checked      int      // 核对的运单数
+	Resubscribed []string // 订阅已失效、重新订阅的运单
+	Unsubscribed []string // 已签收（含退回后签收）、取消订阅的运单
+	Completed    []string // 已签收（含退回后签收）、不再跟踪的运单（包括取消订阅的运单）
+	Failed       []string // 核对或处理失败、下一轮重试的运单
+	Rejected     []string // 连续被申通逐单拒绝达到MaxRejections次、不再跟踪的运单，需要人工处理
+}
+
+// SubscriptionReconciler 定期核对本地认为已订阅的运单与申通实际有效的订阅：
+// 订阅失效且未完结的运单重新订阅，已签收（含退回后签收）的运单取消订阅并停止跟踪，保证推送覆盖完整。
+// 退回途中的运单仍会继续推送轨迹，保持订阅
+type SubscriptionReconciler struct {
+	client *Client
+	cfg    ReconcilerConfig
+
+	mu       sync.Mutex
+	waybills map[string]int // 运单号 -> 连续被逐单拒绝的次数
+}
+
+// NewSubscriptionReconciler 创建订阅对账器
+func NewSubscriptionReconciler(client *Client, cfg ReconcilerConfig) *SubscriptionReconciler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultReconcileInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultPollBatchSize
+	}
+	if cfg.BatchSize > traceSubscribeBatchSize {
+		cfg.BatchSize = traceSubscribeBatchSize
+	}
+	if cfg.MaxRejections <= 0 {
+		cfg.MaxRejections = DefaultMaxSubscribeRejections
+	}
+
+	return &SubscriptionReconciler{
+		client:   client,
+		cfg:      cfg,
+		waybills: make(map[string]int),
+	}
+}
+
+// Add 添加认为已订阅的运单，如服务启动时从数据库加载。
+// 格式不正确的运单号不会加入，避免整批核对每轮都失败，这些运单号作为rejected返回
+func (r *SubscriptionReconciler) Add(waybillNos ...string) (rejected []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, no := range waybillNos {
+		if !ValidWaybillNo(no) {
+			rejected = append(rejected, no)
+			continue
+		}
+		if _, ok := r.waybills[no]; !ok {
+			r.waybills[no] = 0
+		}
+	}
+	return rejected
+}
+
+// Remove 停止跟踪运单，不会取消申通侧的订阅
+func (r *SubscriptionReconciler) Remove(waybillNos ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, no := range waybillNos {
+		delete(r.waybills, no)
+	}
+}
+
+// Len 返回跟踪中的运单数
+func (r *SubscriptionReconciler) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.waybills)
+}
+
+// Run 按配置的间隔执行对账，直到ctx结束
+func (r *SubscriptionReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		report := r.Reconcile(ctx)
+		if r.cfg.OnReport != nil {
+			r.cfg.OnReport(report)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile 执行一轮对账
+func (r *SubscriptionReconciler) Reconcile(ctx context.Context) *ReconcileReport {
+	r.mu.Lock()
+	waybillNos := make([]string, 0, len(r.waybills))
+	for no := range r.waybills {
+		waybillNos = append(waybillNos, no)
+	}
+	r.mu.Unlock()
+	sort.Strings(waybillNos)
+
+	report := &ReconcileReport{}
+	for start := 0; start < len(waybillNos); start += r.cfg.BatchSize {
+		end := start + r.cfg.BatchSize
+		if end > len(waybillNos) {
+			end = len(waybillNos)
+		}
+		if ctx.Err() != nil {
+			report.Failed = append(report.Failed, waybillNos[start:]...)
+			break
+		}
+		r.reconcileBatch(ctx, waybillNos[start:end], report)
+	}
+	r.Remove(report.Completed...)
+	return report
+}
+
+// reconcileBatch 核对一批运单的订阅状态和轨迹，并重新订阅或取消订阅
+func (r *SubscriptionReconciler) reconcileBatch(ctx context.Context, batch []string, report *ReconcileReport) {
+	active, err := r.activeSubscriptions(ctx, batch)
+	if err != nil {
+		r.fail(report, batch, err)
+		return
+	}
+	traceResp, err := r.client.QueryTraceContext(ctx, &TraceQueryRequest{
+		Order:         "asc",
+		WaybillNoList: batch,
+	})
+	if err == nil {
+		err = traceResp.Err()
+	}
+	if err != nil {
+		r.fail(report, batch, err)
+		return
+	}
+	report.Checked += len(batch)
+
+	var resubscribe, unsubscribe, settled []string
+	for _, no := range batch {
+		terminal := CurrentMilestone(traceResp.Data[no]).IsTerminal()
+		switch {
+		case terminal && active[no]:
+			unsubscribe = append(unsubscribe, no)
+		case terminal:
+			report.Completed = append(report.Completed, no)
+		case !active[no]:
+			resubscribe = append(resubscribe, no)
+		default:
+			settled = append(settled, no)
+		}
+	}
+	r.resetRejections(settled)
+
+	if len(resubscribe) > 0 {
+		resp, err := r.client.SubscribeTrace(ctx, &TraceSubscribeRequest{WaybillNoList: resubscribe})
+		ok, failed, err := splitSubscribeResults(resp, err, resubscribe)
+		report.Resubscribed = append(report.Resubscribed, ok...)
+		r.resetRejections(ok)
+		r.settleFailed(report, failed, err)
+	}
+	if len(unsubscribe) > 0 {
+		resp, err := r.client.UnsubscribeTrace(ctx, &TraceUnsubscribeRequest{WaybillNoList: unsubscribe})
+		ok, failed, err := splitSubscribeResults(resp, err, unsubscribe)
+		report.Unsubscribed = append(report.Unsubscribed, ok...)
+		report.Completed = append(report.Completed, ok...)
+		r.settleFailed(report, failed, err)
+	}
+}
+
+// settleFailed 处理订阅或取消订阅失败的运单：整批调用失败时下一轮重试，逐单被拒绝时累计拒绝次数
+func (r *SubscriptionReconciler) settleFailed(report *ReconcileReport, waybillNos []string, err error) {
+	if err != nil {
+		r.fail(report, waybillNos, err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, no := range waybillNos {
+		n, ok := r.waybills[no]
+		if !ok {
+			// 对账期间已被Remove
+			continue
+		}
+		if n+1 >= r.cfg.MaxRejections {
+			delete(r.waybills, no)
+			report.Rejected = append(report.Rejected, no)
+			continue
+		}
+		r.waybills[no] = n + 1
+		report.Failed = append(report.Failed, no)
+	}
+}
+
+// resetRejections 清零运单连续被拒绝的次数
+func (r *SubscriptionReconciler) resetRejections(waybillNos []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, no := range waybillNos {
+		if _, ok := r.waybills[no]; ok {
+			r.waybills[no] = 0
+		}
+	}
+}
+
+// activeSubscriptions 返回批次中订阅有效的运单
+func (r *SubscriptionReconciler) activeSubscriptions(ctx context.Context, batch []string) (map[string]bool, error) {
+	resp, err := r.client.QueryTraceSubscriptions(ctx, &TraceSubscriptionQueryRequest{WaybillNoList: batch})
+	if err == nil {
+		err = resp.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[string]bool, len(resp.Data))
+	for _, s := range resp.Data {
+		if s.Active() {
+			active[s.WaybillNo] = true
+		}
+	}
+	return active, nil
+}
+
+// fail 记录失败的运单并回调错误
+func (r *SubscriptionReconciler) fail(report *ReconcileReport, waybillNos []string, err error) {
+	if len(waybillNos) == 0 {
+		return
+	}
+	report.Failed = append(report.Failed, waybillNos...)
+	if err != nil && r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+// splitSubscribeResults 将订阅或取消订阅的结果拆分为成功和失败的运单，未返回逐单结果时视为整批成功
+func splitSubscribeResults(resp *TraceSubscribeResponse, callErr error, waybillNos []string) (ok, failed []string, err error) {
+	if err = callErr; err == nil {
+		err = resp.Err()
+	}
+	if err != nil {
+		return nil, waybillNos, err
+	}
+
+	failedSet := make(map[string]bool)
+	for _, res := range resp.Data {
+		if res.Success != "true" {
+			failedSet[res.WaybillNo] = true
+		}
+	}
+	for _, no := range waybillNos {
+		if failedSet[no] {
+			failed = append(failed, no)
+		} else {
+			ok = append(ok, no)
+		}
+	}
+	return ok, failed, nil
+}
+
+// String 返回对账结果摘要
+func (r *ReconcileReport) String() string {
+	return fmt.Sprintf("checked=%d resubscribed=%d unsubscribed=%d completed=%d failed=%d rejected=%d",
+		r.Checked, len(r.Resubscribed), len(r.Unsubscribed), len(r.Completed), len(r.Failed), len(r.Rejected))
+}
@@ -0,0 +1,192 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestSubscriptionReconciler(t *testing.T) {
+	const no = "773000000001"
+	traces := func(scanTypes ...string) []sto.TraceInfo {
+		var ts []sto.TraceInfo
+		for _, s := range scanTypes {
+			ts = append(ts, sto.TraceInfo{WaybillNo: no, ScanType: s})
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name             string
+		active           bool
+		traces           []sto.TraceInfo
+		wantResubscribed bool
+		wantUnsubscribed bool
+		wantCompleted    bool
+	}{
+		{name: "active in transit", active: true, traces: traces("收件", "到件")},
+		{name: "expired in transit", traces: traces("收件", "到件"), wantResubscribed: true},
+		{
+			name: "active delivered", active: true, traces: traces("收件", "派件", "签收"),
+			wantUnsubscribed: true, wantCompleted: true,
+		},
+		{name: "expired delivered", traces: traces("收件", "签收"), wantCompleted: true},
+		{name: "active returning", active: true, traces: traces("收件", "退回件", "到件")},
+		{name: "expired returning", traces: traces("收件", "退回件"), wantResubscribed: true},
+		{
+			name: "active returned and signed", active: true, traces: traces("收件", "退回件", "派件", "签收"),
+			wantUnsubscribed: true, wantCompleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subscribed, unsubscribed []string
+			record := func(dst *[]string) stotest.HandlerFunc {
+				return func(content json.RawMessage) (interface{}, error) {
+					var req sto.TraceSubscribeRequest
+					if err := json.Unmarshal(content, &req); err != nil {
+						return nil, err
+					}
+					*dst = append(*dst, req.WaybillNoList...)
+					return nil, nil
+				}
+			}
+			gw := stotest.NewGateway(testAppSecret)
+			gw.SetTraces(no, tt.traces)
+			gw.Handle("STO_TRACE_PLATFORM_SUBSCRIBE_QUERY", func(json.RawMessage) (interface{}, error) {
+				return []sto.TraceSubscription{{WaybillNo: no, Subscribed: boolString(tt.active)}}, nil
+			})
+			gw.Handle("STO_TRACE_PLATFORM_SUBSCRIBE", record(&subscribed))
+			gw.Handle("STO_TRACE_PLATFORM_UNSUBSCRIBE", record(&unsubscribed))
+			client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+			r := sto.NewSubscriptionReconciler(client, sto.ReconcilerConfig{})
+			r.Add(no)
+			report := r.Reconcile(context.Background())
+
+			if report.Checked != 1 || len(report.Failed) != 0 {
+				t.Fatalf("report = %s, want 1 checked and none failed", report)
+			}
+			if got := len(subscribed) == 1; got != tt.wantResubscribed {
+				t.Errorf("resubscribed = %v, want %v", subscribed, tt.wantResubscribed)
+			}
+			if got := len(unsubscribed) == 1; got != tt.wantUnsubscribed {
+				t.Errorf("unsubscribed = %v, want %v", unsubscribed, tt.wantUnsubscribed)
+			}
+			if got := len(report.Completed) == 1; got != tt.wantCompleted {
+				t.Errorf("completed = %v, want %v", report.Completed, tt.wantCompleted)
+			}
+			wantLen := 1
+			if tt.wantCompleted {
+				wantLen = 0
+			}
+			if r.Len() != wantLen {
+				t.Errorf("Len = %d, want %d", r.Len(), wantLen)
+			}
+		})
+	}
+}
+
+func TestSubscriptionReconcilerRejections(t *testing.T) {
+	const no = "773000000001"
+	const (
+		accept = "accept" // 订阅成功
+		reject = "reject" // 申通逐单拒绝
+		busy   = "busy"   // 整批调用失败
+	)
+
+	tests := []struct {
+		name         string
+		rounds       []string // 每轮订阅的结果
+		wantRejected int      // 停止跟踪的轮次，从1开始，0表示一直跟踪
+	}{
+		{name: "rejected repeatedly", rounds: []string{reject, reject, reject, reject}, wantRejected: 3},
+		{name: "transient failures never give up", rounds: []string{busy, busy, busy, busy}},
+		{name: "success resets the count", rounds: []string{reject, reject, accept, reject, reject}},
+		{name: "transient failure does not reset", rounds: []string{reject, busy, reject, reject}, wantRejected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			round := 0
+			gw := stotest.NewGateway(testAppSecret)
+			gw.SetTraces(no, []sto.TraceInfo{{WaybillNo: no, ScanType: "收件"}})
+			gw.Handle("STO_TRACE_PLATFORM_SUBSCRIBE_QUERY", func(json.RawMessage) (interface{}, error) {
+				return []sto.TraceSubscription{{WaybillNo: no, Subscribed: "false"}}, nil
+			})
+			gw.Handle("STO_TRACE_PLATFORM_SUBSCRIBE", func(json.RawMessage) (interface{}, error) {
+				switch tt.rounds[round] {
+				case reject:
+					return []sto.TraceSubscribeResult{{WaybillNo: no, Success: "false", ErrorMsg: "运单号已作废"}}, nil
+				case busy:
+					return nil, &sto.APIError{ErrorCode: sto.ErrorCodeSystemBusy, NeedRetry: true}
+				}
+				return nil, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+			r := sto.NewSubscriptionReconciler(client, sto.ReconcilerConfig{MaxRejections: 3})
+			r.Add(no)
+			for round = 0; round < len(tt.rounds); round++ {
+				report := r.Reconcile(context.Background())
+				parked := round+1 == tt.wantRejected
+				if got := len(report.Rejected) == 1; got != parked {
+					t.Fatalf("round %d: report = %s, want rejected %v", round+1, report, parked)
+				}
+				if parked {
+					if len(report.Failed) != 0 || r.Len() != 0 {
+						t.Errorf("round %d: failed = %v, Len = %d, want parked waybill dropped", round+1, report.Failed, r.Len())
+					}
+					return
+				}
+				if wantFailed := tt.rounds[round] != accept; (len(report.Failed) == 1) != wantFailed {
+					t.Errorf("round %d: failed = %v, want failed %v", round+1, report.Failed, wantFailed)
+				}
+			}
+			if r.Len() != 1 {
+				t.Errorf("Len = %d, want waybill still tracked", r.Len())
+			}
+		})
+	}
+}
+
+func TestSubscriptionReconcilerAddRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name         string
+		waybillNos   []string
+		wantRejected []string
+		wantLen      int
+	}{
+		{name: "all valid", waybillNos: []string{"773000000001", "773000000002"}, wantLen: 2},
+		{
+			name:         "invalid dropped",
+			waybillNos:   []string{"773000000001", "", "ABC123", "77300000000100000"},
+			wantRejected: []string{"", "ABC123", "77300000000100000"},
+			wantLen:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := sto.NewSubscriptionReconciler(sto.NewClient(testAppKey, testAppSecret, testFromCode), sto.ReconcilerConfig{})
+			if rejected := r.Add(tt.waybillNos...); !equalSorted(rejected, tt.wantRejected) {
+				t.Errorf("rejected = %q, want %q", rejected, tt.wantRejected)
+			}
+			if r.Len() != tt.wantLen {
+				t.Errorf("Len = %d, want %d", r.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+// boolString 返回网关使用的布尔字符串
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
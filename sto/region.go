@@ -0,0 +1,93 @@
+package sto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RegionTable 省、市、区县三级行政区划表，用于校验地址中的城市、区县是否属于所填省份。
+// SDK不内置全国行政区划数据，需由调用方从民政部等数据源导出后加载。
+// 表中没有的省份不做校验，城市下没有区县列表时不校验区县，因此可以只加载业务涉及的地区
+type RegionTable struct {
+	provinces map[string]map[string]map[string]bool // 省 -> 市 -> 区县集合
+}
+
+// LoadRegionTable 从JSON读取行政区划表，格式为{"浙江省": {"杭州市": ["西湖区", "余杭区"]}}。
+// 省份可以使用简称，读取时转换为全称
+func LoadRegionTable(r io.Reader) (*RegionTable, error) {
+	var raw map[string]map[string][]string
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decode region table failed: %v", err)
+	}
+
+	t := &RegionTable{provinces: make(map[string]map[string]map[string]bool, len(raw))}
+	for p, cities := range raw {
+		province, ok := NormalizeProvince(p)
+		if !ok {
+			return nil, fmt.Errorf("region table: unknown province %q", p)
+		}
+		if t.provinces[province] == nil {
+			t.provinces[province] = make(map[string]map[string]bool, len(cities))
+		}
+		for city, areas := range cities {
+			city = strings.TrimSpace(city)
+			set := t.provinces[province][city]
+			if set == nil {
+				set = make(map[string]bool, len(areas))
+				t.provinces[province][city] = set
+			}
+			for _, area := range areas {
+				set[strings.TrimSpace(area)] = true
+			}
+		}
+	}
+	return t, nil
+}
+
+// LoadRegionTableFile 从本地JSON文件读取行政区划表
+func LoadRegionTableFile(path string) (*RegionTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open region table failed: %v", err)
+	}
+	defer f.Close()
+	return LoadRegionTable(f)
+}
+
+// Issues 校验寄/收件人的城市是否属于省份、区县是否属于城市，field为字段前缀，如"receiver"。
+// 省份无法识别或不在表中时不做校验，省份本身的问题由OrderContact.Issues报告
+func (t *RegionTable) Issues(field string, o OrderContact) []ValidationIssue {
+	province, ok := NormalizeProvince(o.Province)
+	if !ok || t == nil {
+		return nil
+	}
+	cities := t.provinces[province]
+	city := strings.TrimSpace(o.City)
+	if cities == nil || city == "" {
+		return nil
+	}
+
+	areas, ok := cities[city]
+	if !ok && containsString(municipalityCities[province], city) {
+		// 直辖市的城市有多种写法，区县按该直辖市下的全部区县校验
+		ok = true
+		areas = make(map[string]bool)
+		for _, set := range cities {
+			for area := range set {
+				areas[area] = true
+			}
+		}
+	}
+	if !ok {
+		return []ValidationIssue{{Field: field + ".city", Message: fmt.Sprintf("city %q does not belong to %s", o.City, province)}}
+	}
+
+	area := strings.TrimSpace(o.Area)
+	if area != "" && len(areas) > 0 && !areas[area] {
+		return []ValidationIssue{{Field: field + ".area", Message: fmt.Sprintf("area %q does not belong to %s", o.Area, city)}}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package sto_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+const testRegions = `{
+	"浙江": {"杭州市": ["西湖区", "余杭区"], "宁波市": []},
+	"上海市": {"市辖区": ["青浦区", "浦东新区"]}
+}`
+
+func TestRegionTableIssues(t *testing.T) {
+	regions, err := sto.LoadRegionTable(strings.NewReader(testRegions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := testOrder("O1").Receiver
+
+	tests := []struct {
+		name   string
+		modify func(c *sto.OrderContact)
+		want   []string // 有问题的字段
+	}{
+		{"valid", func(c *sto.OrderContact) {}, nil},
+		{"city outside province", func(c *sto.OrderContact) { c.City = "苏州市" }, []string{"receiver.city"}},
+		{"area outside city", func(c *sto.OrderContact) { c.Area = "鄞州区" }, []string{"receiver.area"}},
+		{"city without area list", func(c *sto.OrderContact) { c.City, c.Area = "宁波市", "鄞州区" }, nil},
+		{"empty area", func(c *sto.OrderContact) { c.Area = "" }, nil},
+		{"province not in table", func(c *sto.OrderContact) { c.Province, c.City, c.Area = "江苏省", "苏州市", "姑苏区" }, nil},
+		{"unknown province left to contact issues", func(c *sto.OrderContact) { c.Province = "火星" }, nil},
+		{"municipality alias", func(c *sto.OrderContact) { c.Province, c.City, c.Area = "上海市", "上海市", "青浦区" }, nil},
+		{"municipality wrong area", func(c *sto.OrderContact) { c.Province, c.City, c.Area = "上海", "上海", "西湖区" }, []string{"receiver.area"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.modify(&c)
+			var fields []string
+			for _, issue := range regions.Issues("receiver", c) {
+				fields = append(fields, issue.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("issues = %v, want %v", regions.Issues("receiver", c), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRegionTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", testRegions, false},
+		{"malformed", `{"浙江省": ["杭州市"]}`, true},
+		{"unknown province", `{"火星": {}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "regions.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := sto.LoadRegionTableFile(path); (err != nil) != tt.wantErr {
+				t.Errorf("LoadRegionTableFile() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := sto.LoadRegionTableFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadRegionTableFile(missing) succeeded")
+	}
+}
+
+func TestCreateOrdersChecksRegions(t *testing.T) {
+	regions, err := sto.LoadRegionTable(strings.NewReader(testRegions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, stotest.NewGateway(testAppSecret))
+
+	bad := testOrder("O2")
+	bad.Receiver.Area = "鄞州区"
+	orders := []*sto.OrderCreateRequest{testOrder("O1"), bad}
+
+	without := client.CreateOrders(context.Background(), orders, sto.BatchOptions{ValidateOnly: true})
+	if !without.OK() {
+		t.Errorf("without regions: failed = %v, want none", without.Failed())
+	}
+
+	report := client.CreateOrders(context.Background(), orders, sto.BatchOptions{ValidateOnly: true, Regions: regions})
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Key != "O2" || len(failed[0].Issues) != 1 || failed[0].Issues[0].Field != "receiver.area" {
+		t.Errorf("with regions: failed = %+v, want O2 with a receiver.area issue", failed)
+	}
+}
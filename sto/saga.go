@@ -0,0 +1,132 @@
+package sto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultCompensationTimeout 默认的补偿操作超时时间
+const DefaultCompensationTimeout = 30 * time.Second
+
+// ErrNothingToCompensate 补偿函数返回该错误表示该步骤实际没有产生需要撤销的结果，不计入Compensated
+var ErrNothingToCompensate = errors.New("nothing to compensate")
+
+// ErrOutcomeUnknown 步骤返回的错误包装该错误表示无法确定步骤是否已经生效（如请求已发出但网络中断），
+// 此时同样执行该步骤的补偿
+var ErrOutcomeUnknown = errors.New("step outcome unknown")
+
+// sagaStep saga中的一步
+type sagaStep struct {
+	name       string
+	action     func(ctx context.Context) error
+	compensate func(ctx context.Context) error
+}
+
+// Saga 按顺序执行多个步骤，某一步失败时按相反顺序补偿已完成的步骤。零值可用，不可并发使用
+type Saga struct {
+	// CompensationTimeout 补偿操作的超时时间，默认DefaultCompensationTimeout。
+	// 补偿不受Run的ctx取消影响，避免调用方超时后留下半完成的状态
+	CompensationTimeout time.Duration
+
+	steps []sagaStep
+}
+
+// Step 添加一个步骤，compensate为撤销该步骤的操作，不需要补偿时为nil
+func (s *Saga) Step(name string, action, compensate func(ctx context.Context) error) *Saga {
+	s.steps = append(s.steps, sagaStep{name: name, action: action, compensate: compensate})
+	return s
+}
+
+// Run 执行全部步骤。某一步失败时补偿已完成的步骤并返回*SagaError，
+// 失败的错误包装了ErrOutcomeUnknown时该步骤本身也会被补偿
+func (s *Saga) Run(ctx context.Context) error {
+	for i, step := range s.steps {
+		if err := ctx.Err(); err != nil {
+			return s.compensate(ctx, i, step.name, err)
+		}
+		if err := step.action(ctx); err != nil {
+			done := i
+			if errors.Is(err, ErrOutcomeUnknown) {
+				done = i + 1
+			}
+			return s.compensate(ctx, done, step.name, err)
+		}
+	}
+	return nil
+}
+
+// compensate 按相反顺序补偿前done个步骤
+func (s *Saga) compensate(ctx context.Context, done int, failedStep string, cause error) error {
+	timeout := s.CompensationTimeout
+	if timeout <= 0 {
+		timeout = DefaultCompensationTimeout
+	}
+	cctx, cancel := context.WithTimeout(detachedContext{ctx}, timeout)
+	defer cancel()
+
+	sagaErr := &SagaError{Step: failedStep, Err: cause}
+	for i := done - 1; i >= 0; i-- {
+		step := s.steps[i]
+		if step.compensate == nil {
+			continue
+		}
+		if err := step.compensate(cctx); err != nil {
+			if errors.Is(err, ErrNothingToCompensate) {
+				continue
+			}
+			sagaErr.CompensationErrors = append(sagaErr.CompensationErrors, StepError{Step: step.name, Err: err})
+			continue
+		}
+		sagaErr.Compensated = append(sagaErr.Compensated, step.name)
+	}
+	return sagaErr
+}
+
+// StepError 某个步骤的错误
+type StepError struct {
+	Step string // 步骤名称
+	Err  error  // 错误
+}
+
+// SagaError saga执行失败
+type SagaError struct {
+	Step               string      // 失败的步骤
+	Err                error       // 失败原因
+	Compensated        []string    // 已成功补偿的步骤，按补偿顺序排列
+	CompensationErrors []StepError // 补偿失败的步骤，需要人工处理
+}
+
+// Error 实现error接口
+func (e *SagaError) Error() string {
+	msg := fmt.Sprintf("saga step %s failed: %v", e.Step, e.Err)
+	if len(e.Compensated) > 0 {
+		msg += fmt.Sprintf(", compensated: %s", strings.Join(e.Compensated, ", "))
+	}
+	for _, ce := range e.CompensationErrors {
+		msg += fmt.Sprintf(", compensate %s failed: %v", ce.Step, ce.Err)
+	}
+	return msg
+}
+
+// Unwrap 返回失败原因
+func (e *SagaError) Unwrap() error {
+	return e.Err
+}
+
+// FullyCompensated 是否所有需要补偿的步骤都已补偿成功
+func (e *SagaError) FullyCompensated() bool {
+	return len(e.CompensationErrors) == 0
+}
+
+// detachedContext 保留父context的值，但不继承其取消和截止时间
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
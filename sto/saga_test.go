@@ -0,0 +1,148 @@
+package sto_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+func TestSagaCompensatesInReverse(t *testing.T) {
+	errStep := errors.New("step failed")
+	errUndo := errors.New("undo failed")
+
+	tests := []struct {
+		name            string
+		failAt          string // 执行失败的步骤，为空表示全部成功
+		unknown         bool   // 失败的步骤无法确定是否已生效
+		undoFails       string // 补偿失败的步骤
+		cancelled       bool   // 运行前取消ctx
+		cancelAfter     string // 该步骤完成后取消ctx
+		wantCalls       []string
+		wantCompensated []string
+		wantUndoErrors  []string
+	}{
+		{
+			name:      "all steps succeed",
+			wantCalls: []string{"a", "b", "c"},
+		},
+		{
+			name:      "first step fails",
+			failAt:    "a",
+			wantCalls: []string{"a"},
+		},
+		{
+			name:            "middle step fails",
+			failAt:          "b",
+			wantCalls:       []string{"a", "b", "undo a"},
+			wantCompensated: []string{"a"},
+		},
+		{
+			name:            "last step fails",
+			failAt:          "c",
+			wantCalls:       []string{"a", "b", "c", "undo b", "undo a"},
+			wantCompensated: []string{"b", "a"},
+		},
+		{
+			name:            "compensation failure continues",
+			failAt:          "c",
+			undoFails:       "b",
+			wantCalls:       []string{"a", "b", "c", "undo b", "undo a"},
+			wantCompensated: []string{"a"},
+			wantUndoErrors:  []string{"b"},
+		},
+		{
+			name:            "step with unknown outcome is compensated",
+			failAt:          "b",
+			unknown:         true,
+			wantCalls:       []string{"a", "b", "undo b", "undo a"},
+			wantCompensated: []string{"b", "a"},
+		},
+		{
+			name:      "cancelled before first step",
+			cancelled: true,
+		},
+		{
+			name:            "cancelled mid-run still compensates",
+			cancelAfter:     "b",
+			wantCalls:       []string{"a", "b", "undo b", "undo a"},
+			wantCompensated: []string{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			var calls []string
+			step := func(name string) (action, undo func(context.Context) error) {
+				action = func(context.Context) error {
+					calls = append(calls, name)
+					if name == tt.cancelAfter {
+						cancel()
+					}
+					if name == tt.failAt {
+						if tt.unknown {
+							return fmt.Errorf("%w: %w", sto.ErrOutcomeUnknown, errStep)
+						}
+						return errStep
+					}
+					return nil
+				}
+				undo = func(ctx context.Context) error {
+					calls = append(calls, "undo "+name)
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					if name == tt.undoFails {
+						return errUndo
+					}
+					return nil
+				}
+				return action, undo
+			}
+
+			var saga sto.Saga
+			for _, name := range []string{"a", "b", "c"} {
+				action, undo := step(name)
+				saga.Step(name, action, undo)
+			}
+
+			err := saga.Run(ctx)
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if tt.failAt == "" && !tt.cancelled && tt.cancelAfter == "" {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+
+			var sagaErr *sto.SagaError
+			if !errors.As(err, &sagaErr) {
+				t.Fatalf("err = %v, want *sto.SagaError", err)
+			}
+			if len(sagaErr.Compensated)+len(tt.wantCompensated) > 0 && !reflect.DeepEqual(sagaErr.Compensated, tt.wantCompensated) {
+				t.Errorf("Compensated = %v, want %v", sagaErr.Compensated, tt.wantCompensated)
+			}
+			var undoErrors []string
+			for _, e := range sagaErr.CompensationErrors {
+				undoErrors = append(undoErrors, e.Step)
+			}
+			if !equalSorted(undoErrors, tt.wantUndoErrors) {
+				t.Errorf("CompensationErrors = %v, want %v", undoErrors, tt.wantUndoErrors)
+			}
+			if sagaErr.FullyCompensated() != (len(tt.wantUndoErrors) == 0) {
+				t.Errorf("FullyCompensated = %v", sagaErr.FullyCompensated())
+			}
+		})
+	}
+}
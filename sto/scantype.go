@@ -0,0 +1,194 @@
+package sto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ScanType 扫描类型字典条目
+type ScanType struct {
+	Code string `json:"code"` // 扫描类型，即轨迹中的scanType值
+	Name string `json:"name"` // 显示名称
+	Desc string `json:"desc"` // 说明
+}
+
+// ScanTypeUnknownName 未识别扫描类型的显示名称
+const ScanTypeUnknownName = "未知"
+
+// defaultScanTypes 内置的扫描类型
+var defaultScanTypes = []ScanType{
+	{Code: "收件", Name: "收件", Desc: "快件已被揽收"},
+	{Code: "发件", Name: "发件", Desc: "快件从网点或中转中心发出"},
+	{Code: "到件", Name: "到件", Desc: "快件到达网点或中转中心"},
+	{Code: "派件", Name: "派件", Desc: "快件正在派送"},
+	{Code: "签收", Name: "签收", Desc: "快件已签收"},
+	{Code: "问题件", Name: "问题件", Desc: "快件出现异常"},
+	{Code: "留仓件", Name: "留仓件", Desc: "快件滞留在网点"},
+	{Code: "退回件", Name: "退回件", Desc: "快件退回寄件人"},
+	{Code: "转寄", Name: "转寄", Desc: "快件转寄至新地址"},
+	{Code: "快件取出", Name: "快件取出", Desc: "快件已从快递柜或驿站取出"},
+	{Code: "派件入柜", Name: "派件入柜", Desc: "快件已放入快递柜"},
+	{Code: "柜机代收", Name: "柜机代收", Desc: "快件由快递柜代收"},
+	{Code: "驿站代收", Name: "驿站代收", Desc: "快件由驿站代收"},
+	{Code: "第三方代派", Name: "第三方代派", Desc: "快件交由第三方派送"},
+}
+
+// maxUnknownScanTypes 记录的已上报未识别扫描类型的上限，超出时淘汰最早上报的
+const maxUnknownScanTypes = 1000
+
+// ScanTypeDictionary 扫描类型字典，可从远程地址或本地文件刷新，可并发使用
+type ScanTypeDictionary struct {
+	mu           sync.RWMutex
+	entries      map[string]ScanType
+	unknown      map[string]bool   // 已上报过的未识别扫描类型
+	unknownOrder []string          // 按上报顺序排列的未识别扫描类型，用于淘汰
+	onUnknown    func(code string) // 发现未识别扫描类型时的回调
+}
+
+// NewScanTypeDictionary 创建包含内置扫描类型的字典
+func NewScanTypeDictionary() *ScanTypeDictionary {
+	d := &ScanTypeDictionary{
+		entries: make(map[string]ScanType, len(defaultScanTypes)),
+		unknown: make(map[string]bool),
+	}
+	for _, t := range defaultScanTypes {
+		d.entries[t.Code] = t
+	}
+	return d
+}
+
+// OnUnknown 设置发现未识别扫描类型时的回调，每个扫描类型只回调一次。
+// 最多记录maxUnknownScanTypes个已上报的扫描类型，超出后最早上报的扫描类型再次出现时会重新回调
+func (d *ScanTypeDictionary) OnUnknown(fn func(code string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onUnknown = fn
+}
+
+// Lookup 查询扫描类型，未识别时返回名称为"未知"的条目和false，并触发OnUnknown回调
+func (d *ScanTypeDictionary) Lookup(code string) (ScanType, bool) {
+	d.mu.RLock()
+	t, ok := d.entries[code]
+	d.mu.RUnlock()
+	if ok {
+		return t, true
+	}
+
+	if code != "" {
+		d.mu.Lock()
+		fn := d.onUnknown
+		first := !d.unknown[code]
+		if first {
+			d.rememberUnknownLocked(code)
+		}
+		d.mu.Unlock()
+
+		if first && fn != nil {
+			fn(code)
+		}
+	}
+	return ScanType{Code: code, Name: ScanTypeUnknownName}, false
+}
+
+// rememberUnknownLocked 记录已上报的未识别扫描类型，超出上限时淘汰最早的，调用方需持有写锁
+func (d *ScanTypeDictionary) rememberUnknownLocked(code string) {
+	for len(d.unknown) >= maxUnknownScanTypes && len(d.unknownOrder) > 0 {
+		// Load后已识别的扫描类型已从unknown删除，顺序表中残留的记录在这里一并清理
+		delete(d.unknown, d.unknownOrder[0])
+		d.unknownOrder = d.unknownOrder[1:]
+	}
+	d.unknown[code] = true
+	d.unknownOrder = append(d.unknownOrder, code)
+}
+
+// Entries 返回字典中的全部条目，按编码排序
+func (d *ScanTypeDictionary) Entries() []ScanType {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	list := make([]ScanType, 0, len(d.entries))
+	for _, t := range d.entries {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// Load 从JSON数组读取扫描类型并合并到字典，编码相同的条目会被覆盖
+func (d *ScanTypeDictionary) Load(r io.Reader) error {
+	var list []ScanType
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return fmt.Errorf("decode scan types failed: %v", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, t := range list {
+		if t.Code == "" {
+			continue
+		}
+		if t.Name == "" {
+			t.Name = t.Code
+		}
+		d.entries[t.Code] = t
+		delete(d.unknown, t.Code)
+	}
+	return nil
+}
+
+// LoadFile 从本地JSON文件刷新字典
+func (d *ScanTypeDictionary) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open scan type file failed: %v", err)
+	}
+	defer f.Close()
+	return d.Load(f)
+}
+
+// Refresh 从远程地址刷新字典，httpClient为nil时使用http.DefaultClient
+func (d *ScanTypeDictionary) Refresh(ctx context.Context, httpClient *http.Client, url string) error {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("create request failed: %v", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("scan type source returned non-200 status code: %d", resp.StatusCode)
+	}
+	return d.Load(resp.Body)
+}
+
+// WithScanTypeDictionary 设置扫描类型字典，查询轨迹时会检查未识别的扫描类型
+func WithScanTypeDictionary(d *ScanTypeDictionary) ClientOption {
+	return func(c *Client) {
+		c.scanTypes = d
+	}
+}
+
+// checkScanTypes 检查轨迹中的扫描类型，未识别时由字典触发回调
+func (c *Client) checkScanTypes(data map[string][]TraceInfo) {
+	if c.scanTypes == nil {
+		return
+	}
+	for _, traces := range data {
+		for _, t := range traces {
+			c.scanTypes.Lookup(t.ScanType)
+		}
+	}
+}
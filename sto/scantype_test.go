@@ -0,0 +1,216 @@
+package sto_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestScanTypeDictionaryLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		load       string // 查询前加载的JSON，为空表示不加载
+		lookups    []string
+		wantKnown  []bool
+		wantName   string // 最后一次查询的名称
+		wantReport []string
+	}{
+		{
+			name:      "builtin",
+			lookups:   []string{"签收"},
+			wantKnown: []bool{true},
+			wantName:  "签收",
+		},
+		{
+			name:       "unknown reported once",
+			lookups:    []string{"海关查验", "海关查验", "", "清关完成"},
+			wantKnown:  []bool{false, false, false, false},
+			wantName:   sto.ScanTypeUnknownName,
+			wantReport: []string{"海关查验", "清关完成"},
+		},
+		{
+			name:      "loaded entry defaults name to code",
+			load:      `[{"code":"海关查验"},{"code":"","name":"ignored"}]`,
+			lookups:   []string{"海关查验"},
+			wantKnown: []bool{true},
+			wantName:  "海关查验",
+		},
+		{
+			name:      "loaded entry overrides builtin",
+			load:      `[{"code":"签收","name":"已签收","desc":"本人签收"}]`,
+			lookups:   []string{"签收"},
+			wantKnown: []bool{true},
+			wantName:  "已签收",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := sto.NewScanTypeDictionary()
+			var reported []string
+			d.OnUnknown(func(code string) { reported = append(reported, code) })
+			if tt.load != "" {
+				if err := d.Load(strings.NewReader(tt.load)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var last sto.ScanType
+			for i, code := range tt.lookups {
+				var ok bool
+				last, ok = d.Lookup(code)
+				if ok != tt.wantKnown[i] {
+					t.Errorf("Lookup(%q) ok = %v, want %v", code, ok, tt.wantKnown[i])
+				}
+			}
+			if last.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", last.Name, tt.wantName)
+			}
+			if !reflect.DeepEqual(reported, tt.wantReport) {
+				t.Errorf("reported = %v, want %v", reported, tt.wantReport)
+			}
+		})
+	}
+}
+
+func TestScanTypeDictionaryUnknownIsBounded(t *testing.T) {
+	d := sto.NewScanTypeDictionary()
+	reports := make(map[string]int)
+	d.OnUnknown(func(code string) { reports[code]++ })
+
+	// 超过上限后最早上报的扫描类型被淘汰，再次出现时重新上报
+	const n = 1500
+	for i := 0; i < n; i++ {
+		d.Lookup(fmt.Sprintf("code-%d", i))
+	}
+	d.Lookup("code-0")
+	d.Lookup(fmt.Sprintf("code-%d", n-1))
+
+	if reports["code-0"] != 2 {
+		t.Errorf("evicted code reported %d times, want 2", reports["code-0"])
+	}
+	if reports[fmt.Sprintf("code-%d", n-1)] != 1 {
+		t.Errorf("recent code reported %d times, want 1", reports[fmt.Sprintf("code-%d", n-1)])
+	}
+}
+
+func TestScanTypeDictionaryLoadAfterReport(t *testing.T) {
+	d := sto.NewScanTypeDictionary()
+	var reported []string
+	d.OnUnknown(func(code string) { reported = append(reported, code) })
+
+	d.Lookup("海关查验")
+	if err := d.Load(strings.NewReader(`[{"code":"海关查验","name":"海关查验"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Lookup("海关查验"); !ok {
+		t.Error("loaded code still unknown")
+	}
+	if len(reported) != 1 {
+		t.Errorf("reported = %v, want one report", reported)
+	}
+}
+
+func TestScanTypeDictionarySources(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "scan_types.json")
+	if err := os.WriteFile(file, []byte(`[{"code":"海关查验","name":"海关查验"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`[{"code":"海关查验","name":"海关查验"}]`))
+		case "/bad":
+			w.Write([]byte(`{"code":"not an array"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		load    func(d *sto.ScanTypeDictionary) error
+		wantErr string
+	}{
+		{name: "file", load: func(d *sto.ScanTypeDictionary) error { return d.LoadFile(file) }},
+		{
+			name:    "missing file",
+			load:    func(d *sto.ScanTypeDictionary) error { return d.LoadFile(filepath.Join(dir, "missing.json")) },
+			wantErr: "open scan type file failed",
+		},
+		{
+			name: "remote",
+			load: func(d *sto.ScanTypeDictionary) error {
+				return d.Refresh(context.Background(), srv.Client(), srv.URL+"/ok")
+			},
+		},
+		{
+			name: "remote non-200",
+			load: func(d *sto.ScanTypeDictionary) error {
+				return d.Refresh(context.Background(), srv.Client(), srv.URL+"/missing")
+			},
+			wantErr: "non-200 status code: 404",
+		},
+		{
+			name: "remote malformed",
+			load: func(d *sto.ScanTypeDictionary) error {
+				return d.Refresh(context.Background(), srv.Client(), srv.URL+"/bad")
+			},
+			wantErr: "decode scan types failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := sto.NewScanTypeDictionary()
+			err := tt.load(d)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if _, ok := d.Lookup("海关查验"); ok {
+					t.Error("failed load changed the dictionary")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := d.Lookup("海关查验"); !ok {
+				t.Error("loaded code not found")
+			}
+		})
+	}
+}
+
+func TestQueryTraceReportsUnknownScanTypes(t *testing.T) {
+	const no = "773000000001"
+	gw := stotest.NewGateway(testAppSecret)
+	gw.SetTraces(no, []sto.TraceInfo{
+		{WaybillNo: no, ScanType: "收件"},
+		{WaybillNo: no, ScanType: "海关查验"},
+	})
+
+	d := sto.NewScanTypeDictionary()
+	var reported []string
+	d.OnUnknown(func(code string) { reported = append(reported, code) })
+	client := newTestClient(t, gw, sto.WithScanTypeDictionary(d))
+
+	if _, err := client.QueryTrace(&sto.TraceQueryRequest{WaybillNoList: []string{no}}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reported, []string{"海关查验"}) {
+		t.Errorf("reported = %v, want [海关查验]", reported)
+	}
+}
@@ -0,0 +1,123 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSortingCodeCacheTTL 大头笔查询结果默认缓存时间
+	DefaultSortingCodeCacheTTL = 24 * time.Hour
+
+	// sortingCodeCacheSize 大头笔缓存最大条目数
+	sortingCodeCacheSize = 10000
+)
+
+// sortingCodeEndpoint 大头笔查询接口
+var sortingCodeEndpoint = endpoint{
+	apiName:  "STO_BIG_CHAR_QUERY",
+	toAppKey: "sto_big_char",
+	toCode:   "sto_big_char",
+
+	emptyFields: emptyFieldsOmit,
+}
+
+// WithSortingCodeCacheTTL 设置大头笔查询结果的缓存时间，0表示不缓存
+func WithSortingCodeCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.sortingCodeTTL = ttl
+	}
+}
+
+// SortingCodeRequest 大头笔查询请求参数
+type SortingCodeRequest struct {
+	SenderProvince   string `json:"senderProvince"`   // 寄件省
+	SenderCity       string `json:"senderCity"`       // 寄件市
+	SenderArea       string `json:"senderArea"`       // 寄件区县
+	SenderAddress    string `json:"senderAddress"`    // 寄件详细地址
+	ReceiverProvince string `json:"receiverProvince"` // 收件省
+	ReceiverCity     string `json:"receiverCity"`     // 收件市
+	ReceiverArea     string `json:"receiverArea"`     // 收件区县
+	ReceiverAddress  string `json:"receiverAddress"`  // 收件详细地址
+}
+
+// Validate 验证请求参数
+func (r *SortingCodeRequest) Validate() error {
+	if r.ReceiverProvince == "" || r.ReceiverCity == "" {
+		return fmt.Errorf("receiverProvince and receiverCity cannot be empty")
+	}
+	if r.ReceiverAddress == "" {
+		return fmt.Errorf("receiverAddress cannot be empty")
+	}
+	return nil
+}
+
+// cacheKey 生成缓存键，忽略首尾空白。使用结构体而不是拼接字符串，避免地址中的分隔符造成不同请求共用缓存
+func (r *SortingCodeRequest) cacheKey() SortingCodeRequest {
+	return SortingCodeRequest{
+		SenderProvince:   strings.TrimSpace(r.SenderProvince),
+		SenderCity:       strings.TrimSpace(r.SenderCity),
+		SenderArea:       strings.TrimSpace(r.SenderArea),
+		SenderAddress:    strings.TrimSpace(r.SenderAddress),
+		ReceiverProvince: strings.TrimSpace(r.ReceiverProvince),
+		ReceiverCity:     strings.TrimSpace(r.ReceiverCity),
+		ReceiverArea:     strings.TrimSpace(r.ReceiverArea),
+		ReceiverAddress:  strings.TrimSpace(r.ReceiverAddress),
+	}
+}
+
+// SortingCodeInfo 大头笔信息
+type SortingCodeInfo struct {
+	BigChar           string `json:"bigChar"`           // 大头笔
+	ShortAddress      string `json:"shortAddress"`      // 三段码
+	PackageCenterCode string `json:"packageCenterCode"` // 集包地代码
+	PackageCenterName string `json:"packageCenterName"` // 集包地名称
+}
+
+// SortingCodeResponse 大头笔查询响应
+type SortingCodeResponse struct {
+	BaseResponse
+	Data *SortingCodeInfo `json:"data"` // 大头笔信息
+
+	// Cached 是否来自本地缓存
+	Cached bool `json:"-"`
+}
+
+// QuerySortingCode 查询收件地址对应的大头笔，用于面单打印和始发分拣。
+// 成功的查询结果会按WithSortingCodeCacheTTL缓存
+func (c *Client) QuerySortingCode(ctx context.Context, req *SortingCodeRequest) (*SortingCodeResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	key := req.cacheKey()
+	if c.sortingCodes != nil {
+		if v, ok := c.sortingCodes.get(key); ok {
+			info := v.(SortingCodeInfo)
+			return &SortingCodeResponse{
+				BaseResponse: BaseResponse{Success: "true"},
+				Data:         &info,
+				Cached:       true,
+			}, nil
+		}
+	}
+
+	res, err := c.execute(ctx, sortingCodeEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &SortingCodeResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	if c.sortingCodes != nil && err == nil && resp.IsSuccess() && resp.Data != nil && resp.Data.BigChar != "" {
+		c.sortingCodes.set(key, *resp.Data)
+	}
+
+	return resp, err
+}
@@ -0,0 +1,160 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+// sortingCodeGateway 返回按收件详细地址生成大头笔的模拟网关，calls记录网关收到的查询次数
+func sortingCodeGateway(calls *int64) *stotest.Gateway {
+	gw := stotest.NewGateway(testAppSecret)
+	gw.Handle("STO_BIG_CHAR_QUERY", func(content json.RawMessage) (interface{}, error) {
+		atomic.AddInt64(calls, 1)
+		var req sto.SortingCodeRequest
+		if err := json.Unmarshal(content, &req); err != nil {
+			return nil, err
+		}
+		if req.ReceiverAddress == "未知地址" {
+			return sto.SortingCodeInfo{}, nil
+		}
+		return sto.SortingCodeInfo{BigChar: req.SenderArea + "/" + req.ReceiverAddress, ShortAddress: "300-200-100"}, nil
+	})
+	return gw
+}
+
+func sortingRequest(senderArea, receiverAddress string) *sto.SortingCodeRequest {
+	return &sto.SortingCodeRequest{
+		SenderProvince:   "上海市",
+		SenderCity:       "上海市",
+		SenderArea:       senderArea,
+		ReceiverProvince: "浙江省",
+		ReceiverCity:     "杭州市",
+		ReceiverAddress:  receiverAddress,
+	}
+}
+
+func TestQuerySortingCodeCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []sto.ClientOption
+		first      *sto.SortingCodeRequest
+		second     *sto.SortingCodeRequest
+		wantCached bool
+		wantCalls  int64
+	}{
+		{
+			name:       "same address hits cache",
+			first:      sortingRequest("青浦区", "西湖区文三路1号"),
+			second:     sortingRequest("青浦区", "西湖区文三路1号"),
+			wantCached: true,
+			wantCalls:  1,
+		},
+		{
+			name:       "surrounding whitespace is ignored",
+			first:      sortingRequest("青浦区", "西湖区文三路1号"),
+			second:     sortingRequest(" 青浦区", "西湖区文三路1号 "),
+			wantCached: true,
+			wantCalls:  1,
+		},
+		{
+			name:      "different address misses cache",
+			first:     sortingRequest("青浦区", "西湖区文三路1号"),
+			second:    sortingRequest("青浦区", "西湖区文三路2号"),
+			wantCalls: 2,
+		},
+		{
+			// 按"|"拼接时两个请求的键相同
+			name:      "separator in address does not collide",
+			first:     sortingRequest("青浦区|", "西湖区"),
+			second:    sortingRequest("青浦区", "|西湖区"),
+			wantCalls: 2,
+		},
+		{
+			name:      "zero ttl disables cache",
+			opts:      []sto.ClientOption{sto.WithSortingCodeCacheTTL(0)},
+			first:     sortingRequest("青浦区", "西湖区文三路1号"),
+			second:    sortingRequest("青浦区", "西湖区文三路1号"),
+			wantCalls: 2,
+		},
+		{
+			name:      "expired entry is refetched",
+			opts:      []sto.ClientOption{sto.WithSortingCodeCacheTTL(time.Nanosecond)},
+			first:     sortingRequest("青浦区", "西湖区文三路1号"),
+			second:    sortingRequest("青浦区", "西湖区文三路1号"),
+			wantCalls: 2,
+		},
+		{
+			name:      "empty result is not cached",
+			first:     sortingRequest("青浦区", "未知地址"),
+			second:    sortingRequest("青浦区", "未知地址"),
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int64
+			client := newTestClient(t, sortingCodeGateway(&calls), tt.opts...)
+
+			first, err := client.QuerySortingCode(context.Background(), tt.first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first.Cached {
+				t.Error("first query reported as cached")
+			}
+			time.Sleep(time.Millisecond)
+
+			second, err := client.QuerySortingCode(context.Background(), tt.second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second.Cached != tt.wantCached {
+				t.Errorf("Cached = %v, want %v", second.Cached, tt.wantCached)
+			}
+			if got := atomic.LoadInt64(&calls); got != tt.wantCalls {
+				t.Errorf("gateway calls = %d, want %d", got, tt.wantCalls)
+			}
+			if want := tt.second.SenderArea + "/" + tt.second.ReceiverAddress; tt.second.ReceiverAddress != "未知地址" && !tt.wantCached && second.Data.BigChar != want {
+				t.Errorf("BigChar = %q, want %q", second.Data.BigChar, want)
+			}
+		})
+	}
+}
+
+func TestQuerySortingCodeGatewayError(t *testing.T) {
+	var calls int64
+	gw := stotest.NewGateway(testAppSecret)
+	gw.Handle("STO_BIG_CHAR_QUERY", func(json.RawMessage) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		return nil, &sto.APIError{ErrorCode: "S07", ErrorMsg: "address not supported"}
+	})
+	client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+	req := sortingRequest("青浦区", "西湖区文三路1号")
+	for i := 0; i < 2; i++ {
+		resp, err := client.QuerySortingCode(context.Background(), req)
+		if err == nil && resp.IsSuccess() {
+			t.Fatal("want failed response")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("gateway calls = %d, want 2: failed results must not be cached", calls)
+	}
+}
+
+func TestQuerySortingCodeValidate(t *testing.T) {
+	client := sto.NewClient(testAppKey, testAppSecret, testFromCode)
+	_, err := client.QuerySortingCode(context.Background(), &sto.SortingCodeRequest{ReceiverProvince: "浙江省"})
+	var invalid *sto.InvalidRequestError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("err = %v, want InvalidRequestError", err)
+	}
+}
@@ -0,0 +1,176 @@
+package stotest
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+// HandlerFunc 处理一个接口的请求，content为请求内容。返回的data作为响应的data字段；
+// 返回*sto.APIError时使用其中的错误码和错误信息，其他错误按请求参数错误处理
+type HandlerFunc func(content json.RawMessage) (data interface{}, err error)
+
+// Gateway 模拟申通开放平台网关：校验签名，按api_name分发请求。
+// 内置轨迹查询接口，返回通过SetTraces设置的轨迹，其他接口可通过Handle注册
+type Gateway struct {
+	appSecret string
+
+	mu       sync.RWMutex
+	traces   map[string][]sto.TraceInfo
+	handlers map[string]HandlerFunc
+	now      func() time.Time
+
+	requestSeq int64
+}
+
+// NewGateway 创建模拟网关，appSecret用于校验请求签名
+func NewGateway(appSecret string) *Gateway {
+	g := &Gateway{
+		appSecret: appSecret,
+		traces:    make(map[string][]sto.TraceInfo),
+		handlers:  make(map[string]HandlerFunc),
+		now:       time.Now,
+	}
+	g.handlers["STO_TRACE_QUERY_COMMON"] = g.queryTrace
+	return g
+}
+
+// NewServer 启动使用模拟网关的HTTPS测试服务器。客户端需使用返回的服务器地址和证书：
+//
+//	srv := stotest.NewServer(gw)
+//	defer srv.Close()
+//	client := sto.NewClient(key, secret, code, sto.WithBaseURL(srv.URL), sto.WithHTTPClient(srv.Client()))
+func NewServer(g *Gateway) *httptest.Server {
+	return httptest.NewTLSServer(g)
+}
+
+// SetTraces 设置运单的轨迹。未来时间的轨迹在到达该时间前不会返回，可以模拟轨迹随时间推进
+func (g *Gateway) SetTraces(waybillNo string, traces []sto.TraceInfo) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.traces[waybillNo] = traces
+}
+
+// WaybillNos 返回已设置轨迹的运单号
+func (g *Gateway) WaybillNos() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	nos := make([]string, 0, len(g.traces))
+	for no := range g.traces {
+		nos = append(nos, no)
+	}
+	sort.Strings(nos)
+	return nos
+}
+
+// SetClock 设置模拟网关的当前时间，用于快进或回放轨迹，默认为time.Now
+func (g *Gateway) SetClock(now func() time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.now = now
+}
+
+// Handle 注册接口处理函数，可覆盖内置的轨迹查询接口
+func (g *Gateway) Handle(apiName string, fn HandlerFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.handlers[apiName] = fn
+}
+
+// ServeHTTP 实现http.Handler接口
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		g.reply(w, nil, &sto.APIError{ErrorCode: sto.ErrorCodeInvalidParam, ErrorMsg: "invalid request"})
+		return
+	}
+	content := r.Form.Get("content")
+
+	h := md5.New()
+	h.Write([]byte(content + g.appSecret))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(digest), []byte(r.Form.Get("data_digest"))) != 1 {
+		g.reply(w, nil, &sto.APIError{ErrorCode: sto.ErrorCodeSignature, ErrorMsg: "签名错误"})
+		return
+	}
+
+	g.mu.RLock()
+	handler, ok := g.handlers[r.Form.Get("api_name")]
+	g.mu.RUnlock()
+	if !ok {
+		g.reply(w, nil, &sto.APIError{ErrorCode: sto.ErrorCodeNoPermission, ErrorMsg: "无权限访问该接口"})
+		return
+	}
+
+	data, err := handler(json.RawMessage(content))
+	g.reply(w, data, err)
+}
+
+// reply 按网关格式写入响应
+func (g *Gateway) reply(w http.ResponseWriter, data interface{}, err error) {
+	resp := struct {
+		sto.BaseResponse
+		Data interface{} `json:"data,omitempty"`
+	}{Data: data}
+	resp.Success = "true"
+	resp.NeedRetry = "false"
+	resp.RequestId = fmt.Sprintf("mock-%d", atomic.AddInt64(&g.requestSeq, 1))
+
+	if err != nil {
+		apiErr, ok := err.(*sto.APIError)
+		if !ok {
+			apiErr = &sto.APIError{ErrorCode: sto.ErrorCodeInvalidParam, ErrorMsg: err.Error()}
+		}
+		resp.Success = "false"
+		resp.ErrorCode = apiErr.ErrorCode
+		resp.ErrorMsg = apiErr.ErrorMsg
+		if apiErr.NeedRetry {
+			resp.NeedRetry = "true"
+		}
+		resp.Data = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// queryTrace 内置的轨迹查询接口
+func (g *Gateway) queryTrace(content json.RawMessage) (interface{}, error) {
+	var req sto.TraceQueryRequest
+	if err := json.Unmarshal(content, &req); err != nil {
+		return nil, fmt.Errorf("invalid content: %v", err)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	now := sto.FormatTime(g.now())
+
+	data := make(map[string][]sto.TraceInfo, len(req.WaybillNoList))
+	for _, no := range req.WaybillNoList {
+		var traces []sto.TraceInfo
+		for _, t := range g.traces[no] {
+			if t.OpTime <= now {
+				traces = append(traces, t)
+			}
+		}
+		if req.Order == "desc" {
+			for i, j := 0, len(traces)-1; i < j; i, j = i+1, j-1 {
+				traces[i], traces[j] = traces[j], traces[i]
+			}
+		}
+		data[no] = traces
+	}
+	return data, nil
+}
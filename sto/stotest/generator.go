@@ -0,0 +1,270 @@
+// Package stotest 提供申通开放平台的模拟网关和模拟轨迹数据，用于在没有真实运单的情况下开发和测试物流跟踪功能
+package stotest
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+// Scenario 模拟运单的物流场景
+type Scenario string
+
+const (
+	ScenarioInTransit      Scenario = "in_transit"       // 运输中，已到达目的地转运中心
+	ScenarioOutForDelivery Scenario = "out_for_delivery" // 派送中
+	ScenarioDelivered      Scenario = "delivered"        // 正常签收
+	ScenarioLocker         Scenario = "locker"           // 放入快递柜后取出
+	ScenarioException      Scenario = "exception"        // 派送时出现问题件并留仓
+	ScenarioReturned       Scenario = "returned"         // 拒收后退回寄件人
+	ScenarioDelayed        Scenario = "delayed"          // 中转滞留，延误一天后继续运输并签收
+)
+
+// Scenarios 全部模拟场景
+var Scenarios = []Scenario{
+	ScenarioInTransit,
+	ScenarioOutForDelivery,
+	ScenarioDelivered,
+	ScenarioLocker,
+	ScenarioException,
+	ScenarioReturned,
+	ScenarioDelayed,
+}
+
+// site 模拟网点或转运中心
+type site struct {
+	province string
+	city     string
+	name     string
+	code     string
+	tel      string
+}
+
+// city 模拟城市，包含一个转运中心和若干网点
+type city struct {
+	hub   site
+	sites []site
+}
+
+// cities 模拟数据使用的城市
+var cities = []city{
+	{
+		hub: site{"上海市", "上海市", "上海转运中心", "200000", "021-39206000"},
+		sites: []site{
+			{"上海市", "上海市", "上海浦东新区公司", "201200", "021-58991234"},
+			{"上海市", "上海市", "上海闵行区公司", "201100", "021-64881234"},
+		},
+	},
+	{
+		hub: site{"浙江省", "杭州市", "杭州转运中心", "310000", "0571-86001234"},
+		sites: []site{
+			{"浙江省", "杭州市", "杭州西湖区公司", "310012", "0571-87651234"},
+			{"浙江省", "杭州市", "杭州余杭区公司", "311100", "0571-89161234"},
+		},
+	},
+	{
+		hub: site{"广东省", "广州市", "广州转运中心", "510000", "020-36001234"},
+		sites: []site{
+			{"广东省", "广州市", "广州天河区公司", "510630", "020-38881234"},
+			{"广东省", "广州市", "广州白云区公司", "510400", "020-86321234"},
+		},
+	},
+	{
+		hub: site{"北京市", "北京市", "北京转运中心", "100000", "010-80481234"},
+		sites: []site{
+			{"北京市", "北京市", "北京朝阳区公司", "100020", "010-65881234"},
+			{"北京市", "北京市", "北京海淀区公司", "100080", "010-62551234"},
+		},
+	},
+	{
+		hub: site{"四川省", "成都市", "成都转运中心", "610000", "028-83001234"},
+		sites: []site{
+			{"四川省", "成都市", "成都武侯区公司", "610041", "028-85121234"},
+			{"四川省", "成都市", "成都锦江区公司", "610021", "028-84451234"},
+		},
+	},
+}
+
+// surnames、givenNames 模拟人员姓名
+var (
+	surnames   = []string{"王", "李", "张", "刘", "陈", "杨", "赵", "黄", "周", "吴"}
+	givenNames = []string{"伟", "芳", "磊", "敏", "强", "静", "军", "丽", "洋", "勇"}
+)
+
+// Generator 生成模拟运单号和轨迹。同一种子生成的数据相同，便于复现问题；不可并发使用
+type Generator struct {
+	rand *rand.Rand
+}
+
+// NewGenerator 使用指定种子创建生成器
+func NewGenerator(seed int64) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(seed))}
+}
+
+// WaybillNo 生成一个格式合法的模拟运单号
+func (g *Generator) WaybillNo() string {
+	return fmt.Sprintf("77%010d", g.rand.Int63n(1e10))
+}
+
+// Scenario 随机选择一个场景
+func (g *Generator) Scenario() Scenario {
+	return Scenarios[g.rand.Intn(len(Scenarios))]
+}
+
+// Traces 生成运单在指定场景下从start开始的完整轨迹，按时间升序排列。
+// 轨迹跨越多天，包含揽收、中转、派送等环节，起止城市随机选择
+func (g *Generator) Traces(waybillNo string, scenario Scenario, start time.Time) []sto.TraceInfo {
+	from := cities[g.rand.Intn(len(cities))]
+	to := cities[g.rand.Intn(len(cities))]
+	for len(cities) > 1 && to.hub.code == from.hub.code {
+		to = cities[g.rand.Intn(len(cities))]
+	}
+	origin := from.sites[g.rand.Intn(len(from.sites))]
+	dest := to.sites[g.rand.Intn(len(to.sites))]
+
+	b := &traceBuilder{g: g, waybillNo: waybillNo, at: g.workingHour(start)}
+	pickup := g.name()
+	b.add(origin, "收件", fmt.Sprintf("您的快件已被【%s】揽收", origin.name), func(t *sto.TraceInfo) {
+		t.BizEmpCode = g.empCode(origin)
+		t.BizEmpName = pickup
+		t.BizEmpPhone = g.mobile()
+		t.Weight = fmt.Sprintf("%.2f", 0.2+g.rand.Float64()*5)
+	})
+	b.transfer(origin, from.hub, 1, 3)
+	if scenario == ScenarioDelayed {
+		b.wait(2, 4)
+		b.add(from.hub, "留仓件", fmt.Sprintf("您的快件在【%s】因运力不足滞留，将尽快发出", from.hub.name), nil)
+		b.wait(20, 26)
+	}
+	b.transfer(from.hub, to.hub, 12, 30)
+	if scenario == ScenarioInTransit {
+		return b.traces
+	}
+	b.transfer(to.hub, dest, 3, 6)
+
+	b.wait(1, 2)
+	courier, courierPhone := g.name(), g.mobile()
+	b.add(dest, "派件", fmt.Sprintf("您的快件正在派送中，派件员：%s，电话：%s", courier, courierPhone), func(t *sto.TraceInfo) {
+		t.BizEmpCode = g.empCode(dest)
+		t.BizEmpName = courier
+		t.BizEmpPhone = courierPhone
+	})
+
+	switch scenario {
+	case ScenarioOutForDelivery:
+		return b.traces
+	case ScenarioException:
+		b.wait(1, 3)
+		b.add(dest, "问题件", "您的快件因收件人电话无人接听暂时无法派送", func(t *sto.TraceInfo) {
+			t.IssueName = "电话无人接听"
+		})
+		b.wait(2, 4)
+		b.add(dest, "留仓件", fmt.Sprintf("您的快件在【%s】留仓，将于次日再次派送", dest.name), nil)
+		return b.traces
+	case ScenarioReturned:
+		b.wait(1, 3)
+		b.add(dest, "问题件", "收件人拒收，快件将退回寄件人", func(t *sto.TraceInfo) {
+			t.IssueName = "收件人拒收"
+		})
+		b.wait(1, 2)
+		b.add(dest, "退回件", "您的快件已办理退回，正在返回寄件人", nil)
+		b.transfer(dest, to.hub, 2, 5)
+		b.transfer(to.hub, from.hub, 12, 30)
+		b.transfer(from.hub, origin, 3, 6)
+		b.wait(1, 2)
+		b.add(origin, "派件", "您的退回件正在派送中", nil)
+		b.wait(1, 4)
+		b.add(origin, "签收", "您的快件已由寄件人签收", func(t *sto.TraceInfo) {
+			t.SignoffPeople = "寄件人"
+		})
+		return b.traces
+	case ScenarioLocker:
+		b.wait(1, 3)
+		b.add(dest, "派件入柜", "您的快件已放入快递柜，请凭取件码取件", nil)
+		b.wait(2, 30)
+		b.add(dest, "快件取出", "您的快件已从快递柜取出", nil)
+		return b.traces
+	}
+
+	b.wait(1, 5)
+	receiver := g.name()
+	b.add(dest, "签收", fmt.Sprintf("您的快件已签收，签收人：%s", receiver), func(t *sto.TraceInfo) {
+		t.SignoffPeople = receiver
+	})
+	return b.traces
+}
+
+// workingHour 将时间调整到当天或次日的营业时间（8点至20点）
+func (g *Generator) workingHour(t time.Time) time.Time {
+	t = t.Truncate(time.Minute)
+	switch h := t.Hour(); {
+	case h < 8:
+		return time.Date(t.Year(), t.Month(), t.Day(), 8, g.rand.Intn(60), 0, 0, t.Location())
+	case h >= 20:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 8, g.rand.Intn(60), 0, 0, t.Location())
+	}
+	return t
+}
+
+// name 生成模拟姓名
+func (g *Generator) name() string {
+	return surnames[g.rand.Intn(len(surnames))] + givenNames[g.rand.Intn(len(givenNames))]
+}
+
+// mobile 生成模拟手机号
+func (g *Generator) mobile() string {
+	return fmt.Sprintf("1%d%09d", 3+g.rand.Intn(7), g.rand.Int63n(1e9))
+}
+
+// empCode 生成模拟员工工号
+func (g *Generator) empCode(s site) string {
+	return fmt.Sprintf("%s%03d", s.code, g.rand.Intn(1000))
+}
+
+// traceBuilder 按时间顺序累积轨迹
+type traceBuilder struct {
+	g         *Generator
+	waybillNo string
+	at        time.Time
+	traces    []sto.TraceInfo
+}
+
+// wait 将时间向后推移min到max小时
+func (b *traceBuilder) wait(min, max int) {
+	minutes := min*60 + b.g.rand.Intn((max-min)*60+1)
+	b.at = b.at.Add(time.Duration(minutes) * time.Minute)
+}
+
+// transfer 生成从from发往to并在to到件的轨迹，运输耗时min到max小时
+func (b *traceBuilder) transfer(from, to site, min, max int) {
+	b.wait(0, 1)
+	b.add(from, "发件", fmt.Sprintf("您的快件已从【%s】发出，下一站【%s】", from.name, to.name), func(t *sto.TraceInfo) {
+		t.NextOrgName = to.name
+		t.NextOrgCode = to.code
+	})
+	b.wait(min, max)
+	b.add(to, "到件", fmt.Sprintf("您的快件已到达【%s】", to.name), nil)
+}
+
+// add 在当前时间添加一条轨迹
+func (b *traceBuilder) add(s site, scanType, memo string, fill func(t *sto.TraceInfo)) {
+	t := sto.TraceInfo{
+		WaybillNo:         b.waybillNo,
+		OpTime:            sto.FormatTime(b.at),
+		OpOrgCode:         s.code,
+		OpOrgName:         s.name,
+		OpOrgProvinceName: s.province,
+		OpOrgCityName:     s.city,
+		OpOrgTel:          s.tel,
+		OpEmpCode:         b.g.empCode(s),
+		OpEmpName:         b.g.name(),
+		ScanType:          scanType,
+		Memo:              memo,
+	}
+	if fill != nil {
+		fill(&t)
+	}
+	b.traces = append(b.traces, t)
+}
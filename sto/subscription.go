@@ -0,0 +1,167 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+)
+
+// traceSubscribeBatchSize 单次订阅的运单号数量上限
+const traceSubscribeBatchSize = 100
+
+// traceSubscribeEndpoint 轨迹订阅接口
+var traceSubscribeEndpoint = endpoint{
+	apiName:  "STO_TRACE_PLATFORM_SUBSCRIBE",
+	toAppKey: "sto_trace_platform",
+	toCode:   "sto_trace_platform",
+}
+
+// traceSubscriptionQueryEndpoint 轨迹订阅状态查询接口
+var traceSubscriptionQueryEndpoint = endpoint{
+	apiName:  "STO_TRACE_PLATFORM_SUBSCRIBE_QUERY",
+	toAppKey: "sto_trace_platform",
+	toCode:   "sto_trace_platform",
+}
+
+// traceUnsubscribeEndpoint 取消轨迹订阅接口
+var traceUnsubscribeEndpoint = endpoint{
+	apiName:  "STO_TRACE_PLATFORM_UNSUBSCRIBE",
+	toAppKey: "sto_trace_platform",
+	toCode:   "sto_trace_platform",
+}
+
+// TraceSubscribeRequest 轨迹订阅请求参数，订阅后轨迹变化会推送到开放平台配置的地址
+type TraceSubscribeRequest struct {
+	WaybillNoList []string `json:"waybillNoList"` // 运单号列表
+}
+
+// Validate 验证请求参数
+func (r *TraceSubscribeRequest) Validate() error {
+	return validateSubscriptionWaybillNos(r.WaybillNoList)
+}
+
+// validateSubscriptionWaybillNos 验证订阅相关接口的运单号列表
+func validateSubscriptionWaybillNos(waybillNos []string) error {
+	if len(waybillNos) == 0 {
+		return fmt.Errorf("waybillNoList cannot be empty")
+	}
+	if len(waybillNos) > traceSubscribeBatchSize {
+		return fmt.Errorf("waybillNoList cannot exceed %d items", traceSubscribeBatchSize)
+	}
+	for _, no := range waybillNos {
+		if !ValidWaybillNo(no) {
+			return fmt.Errorf("invalid waybillNo %q", no)
+		}
+	}
+	return nil
+}
+
+// TraceSubscribeResult 单个运单号的订阅结果
+type TraceSubscribeResult struct {
+	WaybillNo string `json:"waybillNo"` // 运单号
+	Success   string `json:"success"`   // 是否订阅成功
+	ErrorMsg  string `json:"errorMsg"`  // 失败原因
+}
+
+// TraceSubscribeResponse 轨迹订阅响应
+type TraceSubscribeResponse struct {
+	BaseResponse
+	Data []TraceSubscribeResult `json:"data"` // 各运单号的订阅结果
+}
+
+// SubscribeTrace 订阅运单的轨迹推送
+func (c *Client) SubscribeTrace(ctx context.Context, req *TraceSubscribeRequest) (*TraceSubscribeResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, traceSubscribeEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &TraceSubscribeResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
+
+// TraceSubscriptionQueryRequest 轨迹订阅状态查询请求参数
+type TraceSubscriptionQueryRequest struct {
+	WaybillNoList []string `json:"waybillNoList"` // 运单号列表
+}
+
+// Validate 验证请求参数
+func (r *TraceSubscriptionQueryRequest) Validate() error {
+	return validateSubscriptionWaybillNos(r.WaybillNoList)
+}
+
+// TraceSubscription 运单的订阅状态
+type TraceSubscription struct {
+	WaybillNo     string `json:"waybillNo"`     // 运单号
+	Subscribed    string `json:"subscribed"`    // 订阅是否有效，"true"或"false"
+	SubscribeTime string `json:"subscribeTime"` // 订阅时间
+}
+
+// Active 订阅是否有效
+func (s TraceSubscription) Active() bool {
+	return s.Subscribed == "true"
+}
+
+// TraceSubscriptionQueryResponse 轨迹订阅状态查询响应
+type TraceSubscriptionQueryResponse struct {
+	BaseResponse
+	Data []TraceSubscription `json:"data"` // 各运单号的订阅状态，未订阅过的运单可能不返回
+}
+
+// QueryTraceSubscriptions 查询运单的轨迹订阅是否有效
+func (c *Client) QueryTraceSubscriptions(ctx context.Context, req *TraceSubscriptionQueryRequest) (*TraceSubscriptionQueryResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, traceSubscriptionQueryEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &TraceSubscriptionQueryResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
+
+// TraceUnsubscribeRequest 取消轨迹订阅请求参数
+type TraceUnsubscribeRequest struct {
+	WaybillNoList []string `json:"waybillNoList"` // 运单号列表
+}
+
+// Validate 验证请求参数
+func (r *TraceUnsubscribeRequest) Validate() error {
+	return validateSubscriptionWaybillNos(r.WaybillNoList)
+}
+
+// UnsubscribeTrace 取消运单的轨迹推送订阅，结果格式与订阅接口相同
+func (c *Client) UnsubscribeTrace(ctx context.Context, req *TraceUnsubscribeRequest) (*TraceSubscribeResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, traceUnsubscribeEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &TraceSubscribeResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
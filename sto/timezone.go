@@ -0,0 +1,62 @@
+package sto
+
+import (
+	"fmt"
+	"time"
+)
+
+// opTimeLayout 申通接口的时间格式，不带时区
+const opTimeLayout = "2006-01-02 15:04:05"
+
+// beijingLocation 申通接口时间所在的时区（UTC+8，无夏令时）。使用固定偏移而不是time.LoadLocation，
+// 不依赖运行环境的时区数据库，也不受time.Local及其夏令时规则影响。
+// 时区名不用CST，避免与美国中部时间等同名缩写混淆
+var beijingLocation = time.FixedZone("UTC+8", 8*60*60)
+
+// ParseTime 将申通接口返回的时间（北京时间，不带时区）解析为带时区的时间。
+// 不要使用time.Parse或time.ParseInLocation(..., time.Local)解析，否则在非UTC+8的环境中会差若干小时
+func ParseTime(s string) (time.Time, error) {
+	t, err := time.ParseInLocation(opTimeLayout, s, beijingLocation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse sto time %q failed: %v", s, err)
+	}
+	return t, nil
+}
+
+// ToUTC 将申通接口返回的时间解析并转换为UTC时间
+func ToUTC(s string) (time.Time, error) {
+	t, err := ParseTime(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// FormatTime 将任意时区的时间转换为申通接口使用的北京时间字符串，用于请求参数（如寄件时间）
+func FormatTime(t time.Time) string {
+	return t.In(beijingLocation).Format(opTimeLayout)
+}
+
+// Time 返回轨迹的操作时间
+func (t TraceInfo) Time() (time.Time, error) {
+	return ParseTime(t.OpTime)
+}
+
+// WithTimeLocation 设置客户端返回时间（如预计送达时间）使用的时区，默认为北京时间。
+// 只影响时间的显示时区，不改变时间点
+func WithTimeLocation(loc *time.Location) ClientOption {
+	return func(c *Client) {
+		if loc != nil {
+			c.location = loc
+		}
+	}
+}
+
+// ParseTime 解析申通接口返回的时间，并转换为WithTimeLocation设置的时区
+func (c *Client) ParseTime(s string) (time.Time, error) {
+	t, err := ParseTime(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(c.location), nil
+}
@@ -0,0 +1,127 @@
+package sto_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"morning", "2024-03-10 08:30:00", time.Date(2024, 3, 10, 0, 30, 0, 0, time.UTC), false},
+		{"crosses date line in utc", "2024-01-01 07:59:59", time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC), false},
+		{"with timezone suffix", "2024-03-10 08:30:00+08:00", time.Time{}, true},
+		{"date only", "2024-03-10", time.Time{}, true},
+		{"empty", "", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sto.ParseTime(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+			if name, offset := got.Zone(); name != "UTC+8" || offset != 8*60*60 {
+				t.Errorf("zone = %s %d, want UTC+8 28800", name, offset)
+			}
+
+			utc, err := sto.ToUTC(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if utc.Location() != time.UTC || !utc.Equal(tt.want) {
+				t.Errorf("ToUTC(%q) = %s, want %s", tt.in, utc, tt.want)
+			}
+		})
+	}
+
+	if _, err := sto.ToUTC("bad"); err == nil {
+		t.Error("ToUTC(bad) succeeded")
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	// 夏令时期间的纽约时间（UTC-4），使用固定偏移以免依赖时区数据库
+	newYork := time.FixedZone("EDT", -4*60*60)
+
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+	}{
+		{"utc", time.Date(2024, 3, 10, 0, 30, 0, 0, time.UTC), "2024-03-10 08:30:00"},
+		{"utc-4 crosses date", time.Date(2024, 7, 1, 20, 0, 0, 0, newYork), "2024-07-02 08:00:00"},
+		{"drops sub-second", time.Date(2024, 3, 10, 0, 30, 0, 999, time.UTC), "2024-03-10 08:30:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sto.FormatTime(tt.in)
+			if got != tt.want {
+				t.Fatalf("FormatTime = %q, want %q", got, tt.want)
+			}
+			back, err := sto.ParseTime(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !back.Equal(tt.in.Truncate(time.Second)) {
+				t.Errorf("round trip = %s, want %s", back, tt.in)
+			}
+		})
+	}
+}
+
+func TestWithTimeLocation(t *testing.T) {
+	const opTime = "2024-03-10 08:30:00"
+	want := time.Date(2024, 3, 10, 0, 30, 0, 0, time.UTC)
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		wantZone string
+	}{
+		{"default", nil, "UTC+8"},
+		{"custom", tokyo, "UTC+9"},
+		{"utc", time.UTC, "UTC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []sto.ClientOption
+			if tt.loc != nil {
+				opts = append(opts, sto.WithTimeLocation(tt.loc))
+			}
+			client := newTestClient(t, stotest.NewGateway(testAppSecret), opts...)
+
+			got, err := client.ParseTime(opTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("ParseTime = %s, want the same instant as %s", got, want)
+			}
+			if name, _ := got.Zone(); name != tt.wantZone {
+				t.Errorf("zone = %s, want %s", name, tt.wantZone)
+			}
+		})
+	}
+
+	client := newTestClient(t, stotest.NewGateway(testAppSecret), sto.WithTimeLocation(nil))
+	if got, _ := client.ParseTime(opTime); got.Location().String() != "UTC+8" {
+		t.Errorf("WithTimeLocation(nil) location = %s, want default UTC+8", got.Location())
+	}
+}
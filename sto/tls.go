@@ -0,0 +1,128 @@
+package sto
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// pinPrefix 证书指纹前缀
+const pinPrefix = "sha256/"
+
+// WithMinTLSVersion 设置允许的最低TLS版本，如tls.VersionTLS12。
+// 开启后拒绝以明文HTTP访问网关，也不跟随从HTTPS到HTTP的重定向
+func WithMinTLSVersion(version uint16) ClientOption {
+	return func(c *Client) {
+		c.minTLSVersion = version
+	}
+}
+
+// WithPinnedCertificates 固定网关证书，pins为证书公钥（SubjectPublicKeyInfo）的SHA-256指纹，
+// 格式为"sha256/<base64>"，可通过CertificatePin或sto doctor获取。网关证书链中任一证书匹配即可，
+// 未校验证书链时（如InsecureSkipVerify）只匹配叶子证书。建议同时固定备用证书以便证书轮换。开启后同样拒绝明文和降级连接。
+// 仅对网关域名生效，轨迹图片等其他地址只做常规证书校验
+func WithPinnedCertificates(pins ...string) ClientOption {
+	return func(c *Client) {
+		c.pinnedCerts = append(c.pinnedCerts, pins...)
+	}
+}
+
+// CertificatePin 返回证书公钥的SHA-256指纹，格式为"sha256/<base64>"
+func CertificatePin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// strictTLS 是否开启了严格TLS
+func (c *Client) strictTLS() bool {
+	return c.minTLSVersion != 0 || len(c.pinnedCerts) > 0
+}
+
+// configureTLS 在transport上应用最低TLS版本和证书固定
+func (c *Client) configureTLS(transport *http.Transport) error {
+	pins := make(map[string]bool, len(c.pinnedCerts))
+	for _, pin := range c.pinnedCerts {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, pinPrefix))
+		if err != nil || len(raw) != sha256.Size {
+			return fmt.Errorf("invalid certificate pin %q", pin)
+		}
+		pins[pinPrefix+base64.StdEncoding.EncodeToString(raw)] = true
+	}
+
+	cfg := transport.TLSClientConfig
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+	if c.minTLSVersion != 0 && cfg.MinVersion < c.minTLSVersion {
+		cfg.MinVersion = c.minTLSVersion
+	}
+	if len(pins) > 0 {
+		gatewayHost := c.gatewayHostname()
+		verify := cfg.VerifyConnection
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			// 网关地址为IP时握手不带SNI，ServerName为空，此时同样校验指纹
+			ipGateway := net.ParseIP(gatewayHost) != nil && cs.ServerName == ""
+			if cs.ServerName != gatewayHost && !ipGateway {
+				return nil
+			}
+			return verifyPins(cs, pins)
+		}
+	}
+	transport.TLSClientConfig = cfg
+	return nil
+}
+
+// verifyPins 检查证书链中是否有证书匹配固定的指纹
+func verifyPins(cs tls.ConnectionState, pins map[string]bool) error {
+	chains := cs.VerifiedChains
+	if len(chains) == 0 && len(cs.PeerCertificates) > 0 {
+		// 未校验证书链时，叶子之后的证书可由服务端任意附带，不能证明叶子由其签发，只匹配叶子证书
+		chains = [][]*x509.Certificate{cs.PeerCertificates[:1]}
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if pins[CertificatePin(cert)] {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("certificate of %s does not match any pinned certificate", cs.ServerName)
+}
+
+// rejectDowngradeRedirect 拒绝从HTTPS重定向到HTTP，其余情况交给next处理
+func rejectDowngradeRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("refusing redirect to plaintext url %s", req.URL.Redacted())
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+// gatewayHostname 返回网关域名
+func (c *Client) gatewayHostname() string {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
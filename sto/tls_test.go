@@ -0,0 +1,190 @@
+package sto_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestPinnedCertificates(t *testing.T) {
+	const no = "773000000001"
+	gw := stotest.NewGateway(testAppSecret)
+	gw.SetTraces(no, []sto.TraceInfo{{WaybillNo: no, ScanType: "收件"}})
+	srv := httptest.NewUnstartedServer(gw)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // 指纹不匹配时服务端会记录握手失败
+	srv.StartTLS()
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// httptest的证书同时签发给example.com和127.0.0.1
+	namedURL := "https://example.com:" + u.Port()
+	staticHosts := sto.WithStaticHosts(map[string][]string{"example.com": {u.Hostname()}})
+
+	good := sto.CertificatePin(srv.Certificate())
+	other := sha256.Sum256([]byte("backup key"))
+	backup := "sha256/" + base64.StdEncoding.EncodeToString(other[:])
+
+	tests := []struct {
+		name    string
+		baseURL string
+		opts    []sto.ClientOption
+		wantErr string
+	}{
+		{
+			name:    "matching pin",
+			baseURL: namedURL,
+			opts:    []sto.ClientOption{staticHosts, sto.WithPinnedCertificates(good)},
+		},
+		{
+			name:    "backup pin alongside matching pin",
+			baseURL: namedURL,
+			opts:    []sto.ClientOption{staticHosts, sto.WithPinnedCertificates(backup, good)},
+		},
+		{
+			name:    "mismatched pin",
+			baseURL: namedURL,
+			opts:    []sto.ClientOption{staticHosts, sto.WithPinnedCertificates(backup)},
+			wantErr: "does not match any pinned certificate",
+		},
+		{
+			name:    "mismatched pin on ip gateway",
+			baseURL: srv.URL,
+			opts:    []sto.ClientOption{sto.WithPinnedCertificates(backup)},
+			wantErr: "does not match any pinned certificate",
+		},
+		{
+			name:    "malformed pin",
+			baseURL: namedURL,
+			opts:    []sto.ClientOption{staticHosts, sto.WithPinnedCertificates("sha256/not-base64")},
+			wantErr: "invalid certificate pin",
+		},
+		{
+			name:    "minimum version above server",
+			baseURL: namedURL,
+			opts: []sto.ClientOption{
+				staticHosts,
+				sto.WithMinTLSVersion(tls.VersionTLS13),
+				sto.WithPinnedCertificates(good),
+			},
+		},
+		{
+			name:    "plaintext gateway refused",
+			baseURL: "http://example.com:" + u.Port(),
+			opts:    []sto.ClientOption{staticHosts, sto.WithPinnedCertificates(good)},
+			wantErr: "refusing plaintext request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]sto.ClientOption{
+				sto.WithBaseURL(tt.baseURL),
+				sto.WithHTTPClient(srv.Client()),
+				sto.WithLogger(log.New(io.Discard, "", 0)),
+				sto.WithMaxRetries(0),
+			}, tt.opts...)
+			client := sto.NewClient(testAppKey, testAppSecret, testFromCode, opts...)
+
+			resp, err := client.QueryTrace(&sto.TraceQueryRequest{WaybillNoList: []string{no}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data[no]) != 1 {
+				t.Errorf("got %d traces, want 1", len(resp.Data[no]))
+			}
+		})
+	}
+}
+
+func TestPinnedCertificatesUnverifiedChain(t *testing.T) {
+	const no = "773000000001"
+	gw := stotest.NewGateway(testAppSecret)
+	gw.SetTraces(no, []sto.TraceInfo{{WaybillNo: no, ScanType: "收件"}})
+	srv := httptest.NewUnstartedServer(gw)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	// 服务端使用自签的叶子证书，并在其后附带固定的证书，未校验证书链时不能据此通过
+	pinned := srv.Certificate()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.TLS.Certificates = []tls.Certificate{{Certificate: [][]byte{leafDER, pinned.Raw}, PrivateKey: key}}
+
+	tests := []struct {
+		name    string
+		pin     string
+		wantErr bool
+	}{
+		{"pin of appended certificate", sto.CertificatePin(pinned), true},
+		{"pin of leaf", sto.CertificatePin(leaf), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			}}
+			client := sto.NewClient(testAppKey, testAppSecret, testFromCode,
+				sto.WithBaseURL(srv.URL),
+				sto.WithHTTPClient(httpClient),
+				sto.WithLogger(log.New(io.Discard, "", 0)),
+				sto.WithMaxRetries(0),
+				sto.WithPinnedCertificates(tt.pin),
+			)
+
+			_, err := client.QueryTrace(&sto.TraceQueryRequest{WaybillNoList: []string{no}})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "does not match any pinned certificate") {
+					t.Errorf("err = %v, want pin mismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package sto
+
+import (
+	"time"
+)
+
+// TraceCache 保存运单最近一次查到的轨迹，用于网关不可用时返回降级数据。可自行实现以使用Redis等外部存储。
+// 轨迹始终按时间升序保存，降级返回时再按请求的排序方式调整
+type TraceCache interface {
+	// Get 读取运单轨迹及其查询时间
+	Get(waybillNo string) (traces []TraceInfo, fetchedAt time.Time, ok bool)
+	// Set 保存运单轨迹及其查询时间
+	Set(waybillNo string, traces []TraceInfo, fetchedAt time.Time)
+}
+
+// cachedTraces 内存缓存中的轨迹
+type cachedTraces struct {
+	traces    []TraceInfo
+	fetchedAt time.Time
+}
+
+// memoryTraceCache 基于内存的TraceCache
+type memoryTraceCache struct {
+	cache *ttlCache
+}
+
+// NewMemoryTraceCache 创建内存轨迹缓存，ttl为轨迹最长保留时间，maxEntries<=0表示不限制运单数
+func NewMemoryTraceCache(ttl time.Duration, maxEntries int) TraceCache {
+	return &memoryTraceCache{cache: newTTLCache(ttl, maxEntries)}
+}
+
+// Get 实现TraceCache接口
+func (m *memoryTraceCache) Get(waybillNo string) ([]TraceInfo, time.Time, bool) {
+	v, ok := m.cache.get(waybillNo)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	e := v.(cachedTraces)
+	return e.traces, e.fetchedAt, true
+}
+
+// Set 实现TraceCache接口
+func (m *memoryTraceCache) Set(waybillNo string, traces []TraceInfo, fetchedAt time.Time) {
+	m.cache.set(waybillNo, cachedTraces{traces: traces, fetchedAt: fetchedAt})
+}
+
+// WithStaleTraceFallback 开启轨迹降级：每次成功查询的轨迹写入cache，网关不可用（熔断、网络错误、
+// 重试用尽）时返回cache中的轨迹，并将响应标记为Stale
+func WithStaleTraceFallback(cache TraceCache) ClientOption {
+	return func(c *Client) {
+		c.traceCache = cache
+	}
+}
+
+// storeTraces 按升序保存查到的轨迹，order为查询时的排序方式
+func (c *Client) storeTraces(order string, data map[string][]TraceInfo) {
+	if c.traceCache == nil {
+		return
+	}
+	now := time.Now()
+	for no, traces := range data {
+		if len(traces) > 0 {
+			// 复制一份，避免调用方修改响应中的轨迹时影响缓存
+			c.traceCache.Set(no, orderTraces(append([]TraceInfo(nil), traces...), order), now)
+		}
+	}
+}
+
+// orderTraces 在升序和请求的排序方式之间转换轨迹顺序，order为desc时原地反转，否则原样返回
+func orderTraces(traces []TraceInfo, order string) []TraceInfo {
+	if order == "desc" {
+		for i, j := 0, len(traces)-1; i < j; i, j = i+1, j-1 {
+			traces[i], traces[j] = traces[j], traces[i]
+		}
+	}
+	return traces
+}
+
+// staleTraces 从缓存构造降级响应，没有任何缓存数据时返回nil
+func (c *Client) staleTraces(req *TraceQueryRequest) *TraceQueryResponse {
+	if c.traceCache == nil {
+		return nil
+	}
+
+	resp := &TraceQueryResponse{
+		BaseResponse: BaseResponse{Success: "true"},
+		Data:         make(map[string][]TraceInfo),
+		Stale:        true,
+	}
+	for _, no := range req.WaybillNoList {
+		traces, fetchedAt, ok := c.traceCache.Get(no)
+		if !ok {
+			continue
+		}
+		resp.Data[no] = orderTraces(append([]TraceInfo(nil), traces...), req.Order)
+		if resp.StaleAt.IsZero() || fetchedAt.Before(resp.StaleAt) {
+			resp.StaleAt = fetchedAt
+		}
+	}
+	if len(resp.Data) == 0 {
+		return nil
+	}
+	return resp
+}
@@ -0,0 +1,116 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// WithResolver 设置解析网关域名使用的DNS解析器，如指向内网DNS。其他域名仍使用默认解析
+func WithResolver(resolver *net.Resolver) ClientOption {
+	return func(c *Client) {
+		c.resolver = resolver
+	}
+}
+
+// WithStaticHosts 将网关域名固定解析到指定IP，如{"cloudinter-linkgateway.sto.cn": {"203.0.113.10"}}。
+// 多个IP按顺序尝试；TLS证书仍按域名校验。只对网关域名生效，hosts会被复制，之后修改不影响客户端
+func WithStaticHosts(hosts map[string][]string) ClientOption {
+	return func(c *Client) {
+		c.staticHosts = make(map[string][]string, len(hosts))
+		for host, ips := range hosts {
+			c.staticHosts[host] = append([]string(nil), ips...)
+		}
+	}
+}
+
+// configureTransport 根据选项配置HTTP客户端的连接方式
+func (c *Client) configureTransport() {
+	customDial := c.resolver != nil || len(c.staticHosts) > 0
+	strictTLS := c.strictTLS()
+	if !customDial && !strictTLS {
+		return
+	}
+
+	transport, ok := c.baseTransport()
+	if !ok {
+		if strictTLS {
+			// 无法保证TLS要求时所有网关请求直接失败，而不是降级为普通连接
+			c.transportErr = fmt.Errorf("WithMinTLSVersion/WithPinnedCertificates require http client transport to be *http.Transport")
+		}
+		if customDial {
+			c.logf("WithResolver/WithStaticHosts ignored: custom http client transport is not *http.Transport")
+		}
+		return
+	}
+	if customDial {
+		next := transport.DialContext
+		if next == nil {
+			next = (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext
+		}
+		transport.DialContext = c.dialContext(next)
+	}
+
+	client := *c.httpClient
+	if strictTLS {
+		if err := c.configureTLS(transport); err != nil {
+			c.transportErr = err
+			return
+		}
+		client.CheckRedirect = rejectDowngradeRedirect(client.CheckRedirect)
+	}
+	client.Transport = transport
+	c.httpClient = &client
+}
+
+// baseTransport 返回可修改的Transport副本，自定义RoundTripper无法修改时返回false
+func (c *Client) baseTransport() (*http.Transport, bool) {
+	switch t := c.httpClient.Transport.(type) {
+	case nil:
+		return http.DefaultTransport.(*http.Transport).Clone(), true
+	case *http.Transport:
+		return t.Clone(), true
+	}
+	return nil, false
+}
+
+// dialContext 返回拨号函数：网关域名按静态解析表或自定义解析器解析后依次尝试各个IP，
+// 其他地址直接交给next，next为Transport原有的拨号函数
+func (c *Client) dialContext(next func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	gatewayHost := c.gatewayHostname()
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || host != gatewayHost {
+			return next(ctx, network, addr)
+		}
+
+		ips, ok := c.staticHosts[host]
+		if !ok && c.resolver != nil && net.ParseIP(host) == nil {
+			if ips, err = c.resolver.LookupHost(ctx, host); err != nil {
+				return nil, err
+			}
+			ok = true
+		}
+		if !ok {
+			return next(ctx, network, addr)
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no address for host %s", host)
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := next(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
@@ -0,0 +1,128 @@
+package sto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDialContextScopedToGateway(t *testing.T) {
+	hosts := map[string][]string{
+		"gateway.test": {"192.0.2.1", "192.0.2.2"},
+		"other.test":   {"192.0.2.9"},
+		"empty.test":   {},
+	}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		addr    string
+		refuse  map[string]bool // 拨号失败的地址
+		want    []string        // 依次拨号的地址
+		wantErr bool
+	}{
+		{
+			name:    "gateway uses static hosts",
+			baseURL: "https://gateway.test/open/api",
+			addr:    "gateway.test:443",
+			want:    []string{"192.0.2.1:443"},
+		},
+		{
+			name:    "gateway falls back to next ip",
+			baseURL: "https://gateway.test/open/api",
+			addr:    "gateway.test:443",
+			refuse:  map[string]bool{"192.0.2.1:443": true},
+			want:    []string{"192.0.2.1:443", "192.0.2.2:443"},
+		},
+		{
+			name:    "other host is not rewritten",
+			baseURL: "https://gateway.test/open/api",
+			addr:    "other.test:443",
+			want:    []string{"other.test:443"},
+		},
+		{
+			name:    "gateway without entry dials directly",
+			baseURL: "https://unlisted.test/open/api",
+			addr:    "unlisted.test:443",
+			want:    []string{"unlisted.test:443"},
+		},
+		{
+			name:    "empty entry fails",
+			baseURL: "https://empty.test/open/api",
+			addr:    "empty.test:443",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("key", "secret", "code", WithBaseURL(tt.baseURL), WithStaticHosts(hosts))
+			var dialed []string
+			next := func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialed = append(dialed, addr)
+				if tt.refuse[addr] {
+					return nil, errors.New("connection refused")
+				}
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			}
+
+			conn, err := c.dialContext(next)(context.Background(), "tcp", tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+			if !reflect.DeepEqual(dialed, tt.want) {
+				t.Errorf("dialed %v, want %v", dialed, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithStaticHostsCopiesMap(t *testing.T) {
+	hosts := map[string][]string{"gateway.test": {"192.0.2.1"}}
+	c := NewClient("key", "secret", "code", WithStaticHosts(hosts))
+	hosts["gateway.test"][0] = "192.0.2.99"
+	hosts["other.test"] = []string{"192.0.2.2"}
+
+	want := map[string][]string{"gateway.test": {"192.0.2.1"}}
+	if !reflect.DeepEqual(c.staticHosts, want) {
+		t.Errorf("staticHosts = %v, want %v", c.staticHosts, want)
+	}
+}
+
+func TestStaticHostsWrapUserDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	var dialed []string
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	c := NewClient("key", "secret", "code",
+		WithBaseURL("http://gateway.test:"+port),
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithStaticHosts(map[string][]string{"gateway.test": {"127.0.0.1"}}),
+	)
+
+	resp, err := c.httpClient.Get(c.baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := []string{"127.0.0.1:" + port}; !reflect.DeepEqual(dialed, want) {
+		t.Errorf("user dialer saw %v, want %v", dialed, want)
+	}
+}
@@ -0,0 +1,105 @@
+package sto
+
+import (
+	"sync"
+)
+
+// UsageKey 用量统计的账号维度
+type UsageKey struct {
+	AppKey   string // 账号的APP KEY
+	FromCode string // 商户编码
+}
+
+// Usage 一个账号的网关调用用量
+type Usage struct {
+	Requests int64 // HTTP请求次数，每次重试单独计数
+	Failures int64 // 未收到响应的请求次数（网络错误、超时等）
+
+	// RequestURLBytes 请求地址的字节数，业务内容和签名都在其中。不含HTTP请求头和TLS开销，不等于实际网络流量
+	RequestURLBytes int64
+	// ResponseBodyBytes 响应体的字节数，不含HTTP响应头
+	ResponseBodyBytes int64
+}
+
+// add 累加用量
+func (u *Usage) add(o Usage) {
+	u.Requests += o.Requests
+	u.Failures += o.Failures
+	u.RequestURLBytes += o.RequestURLBytes
+	u.ResponseBodyBytes += o.ResponseBodyBytes
+}
+
+// UsageMeter 按账号统计网关调用用量，可在多个客户端间共享，用于多租户平台按客户计费或控制预算。可并发使用
+type UsageMeter struct {
+	mu    sync.Mutex
+	usage map[UsageKey]*Usage
+}
+
+// NewUsageMeter 创建用量统计器
+func NewUsageMeter() *UsageMeter {
+	return &UsageMeter{usage: make(map[UsageKey]*Usage)}
+}
+
+// record 记录一次请求
+func (m *UsageMeter) record(key UsageKey, u Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	total, ok := m.usage[key]
+	if !ok {
+		total = &Usage{}
+		m.usage[key] = total
+	}
+	total.add(u)
+}
+
+// Usage 返回指定账号的累计用量
+func (m *UsageMeter) Usage(key UsageKey) Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.usage[key]; ok {
+		return *u
+	}
+	return Usage{}
+}
+
+// Snapshot 返回所有账号的累计用量
+func (m *UsageMeter) Snapshot() map[UsageKey]Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[UsageKey]Usage, len(m.usage))
+	for k, u := range m.usage {
+		snapshot[k] = *u
+	}
+	return snapshot
+}
+
+// Reset 返回所有账号的累计用量并清零，用于按周期结算
+func (m *UsageMeter) Reset() map[UsageKey]Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[UsageKey]Usage, len(m.usage))
+	for k, u := range m.usage {
+		snapshot[k] = *u
+	}
+	m.usage = make(map[UsageKey]*Usage)
+	return snapshot
+}
+
+// WithUsageMeter 将客户端的网关调用用量记录到meter，多个客户端可共享同一个meter
+func WithUsageMeter(meter *UsageMeter) ClientOption {
+	return func(c *Client) {
+		c.usage = meter
+	}
+}
+
+// recordUsage 记录一次网关请求的用量
+func (c *Client) recordUsage(urlBytes, bodyBytes int, failed bool) {
+	if c.usage == nil {
+		return
+	}
+	u := Usage{Requests: 1, RequestURLBytes: int64(urlBytes), ResponseBodyBytes: int64(bodyBytes)}
+	if failed {
+		u.Failures = 1
+	}
+	c.usage.record(UsageKey{AppKey: c.AppKey, FromCode: c.FromCode}, u)
+}
@@ -0,0 +1,79 @@
+package sto_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+)
+
+func TestUsageMeter(t *testing.T) {
+	const (
+		ok   = `{"success":"true","needRetry":"false","data":{"A":[]}}`
+		busy = `{"success":"false","needRetry":"true","errorCode":"009","data":null}`
+	)
+	hangUp := func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}
+
+	tests := []struct {
+		name         string
+		handler      http.Handler
+		maxRetries   int
+		wantRequests int64
+		wantFailures int64
+		wantBody     int64
+	}{
+		{
+			name:         "single request",
+			handler:      sequence(rawJSON(ok)),
+			wantRequests: 1,
+			wantBody:     int64(len(ok)),
+		},
+		{
+			name:         "retries counted separately",
+			handler:      sequence(rawJSON(busy), rawJSON(ok)),
+			maxRetries:   1,
+			wantRequests: 2,
+			wantBody:     int64(len(busy) + len(ok)),
+		},
+		{
+			name:         "connection failure",
+			handler:      sequence(hangUp),
+			wantRequests: 1,
+			wantFailures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meter := sto.NewUsageMeter()
+			client := newTestClient(t, tt.handler, sto.WithUsageMeter(meter), sto.WithMaxRetries(tt.maxRetries))
+			client.QueryTraceContext(context.Background(), &sto.TraceQueryRequest{WaybillNoList: []string{"A"}})
+
+			snapshot := meter.Snapshot()
+			if len(snapshot) != 1 {
+				t.Fatalf("got %d accounts, want 1", len(snapshot))
+			}
+			u := meter.Usage(sto.UsageKey{AppKey: testAppKey, FromCode: testFromCode})
+			if u.Requests != tt.wantRequests || u.Failures != tt.wantFailures {
+				t.Errorf("requests = %d, failures = %d, want %d, %d", u.Requests, u.Failures, tt.wantRequests, tt.wantFailures)
+			}
+			if u.ResponseBodyBytes != tt.wantBody {
+				t.Errorf("ResponseBodyBytes = %d, want %d", u.ResponseBodyBytes, tt.wantBody)
+			}
+			if u.RequestURLBytes <= 0 {
+				t.Errorf("RequestURLBytes = %d, want > 0", u.RequestURLBytes)
+			}
+
+			meter.Reset()
+			if got := meter.Snapshot(); len(got) != 0 {
+				t.Errorf("after Reset: %v, want empty", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package sto
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationIssue 客户端校验发现的问题
+type ValidationIssue struct {
+	Field   string // 字段，如"receiver.mobile"
+	Message string // 问题描述
+}
+
+// String 返回"字段: 问题"形式的描述
+func (i ValidationIssue) String() string {
+	return i.Field + ": " + i.Message
+}
+
+// ValidationError 请求参数校验失败，包含全部问题
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+// Error 实现error接口
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		msgs[i] = issue.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// issuesError 没有问题时返回nil，否则返回*ValidationError
+func issuesError(issues []ValidationIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
+
+var (
+	// mobileNoPattern 手机号码
+	mobileNoPattern = regexp.MustCompile(`^1[3-9]\d{9}$`)
+
+	// waybillNoPattern 申通运单号
+	waybillNoPattern = regexp.MustCompile(`^\d{10,15}$`)
+)
+
+// provinces 省级行政区全称
+var provinces = []string{
+	"北京市", "天津市", "上海市", "重庆市",
+	"河北省", "山西省", "辽宁省", "吉林省", "黑龙江省", "江苏省", "浙江省", "安徽省",
+	"福建省", "江西省", "山东省", "河南省", "湖北省", "湖南省", "广东省", "海南省",
+	"四川省", "贵州省", "云南省", "陕西省", "甘肃省", "青海省", "台湾省",
+	"内蒙古自治区", "广西壮族自治区", "西藏自治区", "宁夏回族自治区", "新疆维吾尔自治区",
+	"香港特别行政区", "澳门特别行政区",
+}
+
+// municipalityCities 直辖市的市级名称：城市与直辖市同名，或使用行政区划代码中的"市辖区"、"县"
+var municipalityCities = map[string][]string{
+	"北京市": {"北京市", "北京", "市辖区"},
+	"天津市": {"天津市", "天津", "市辖区"},
+	"上海市": {"上海市", "上海", "市辖区"},
+	"重庆市": {"重庆市", "重庆", "市辖区", "县"},
+}
+
+// NormalizeProvince 将省份名称（含"浙江"、"广西"等简称）转换为全称，无法识别时返回false
+func NormalizeProvince(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) < 2 {
+		return "", false
+	}
+	for _, p := range provinces {
+		if p == name || strings.HasPrefix(p, name) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// ValidWaybillNo 判断运单号格式是否正确
+func ValidWaybillNo(waybillNo string) bool {
+	return waybillNoPattern.MatchString(waybillNo)
+}
+
+// Issues 校验寄/收件人信息，field为字段前缀，如"receiver"。
+// 地址只校验省份能否识别、直辖市的城市是否与省份一致以及城市、详细地址非空，
+// 不校验其他省份下的城市、区县是否属于该省
+func (o OrderContact) Issues(field string) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(name, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Field: field + "." + name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(o.Name) == "" {
+		add("name", "cannot be empty")
+	}
+	switch {
+	case o.Mobile == "" && o.Tel == "":
+		add("mobile", "mobile or tel is required")
+	case o.Mobile != "" && !mobileNoPattern.MatchString(o.Mobile):
+		add("mobile", "invalid mobile number %q", o.Mobile)
+	}
+	province, ok := NormalizeProvince(o.Province)
+	if !ok {
+		add("province", "unknown province %q", o.Province)
+	}
+	city := strings.TrimSpace(o.City)
+	switch {
+	case city == "":
+		add("city", "cannot be empty")
+	case ok && municipalityCities[province] != nil && !containsString(municipalityCities[province], city):
+		add("city", "city %q does not belong to %s", o.City, province)
+	}
+	if strings.TrimSpace(o.Address) == "" {
+		add("address", "cannot be empty")
+	}
+	return issues
+}
+
+// containsString 判断ss中是否包含s
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
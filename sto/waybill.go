@@ -0,0 +1,300 @@
+package sto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWaybillNoTTL 运单号池中单号的默认有效期
+	DefaultWaybillNoTTL = 72 * time.Hour
+
+	// waybillReturnBatchSize 单次回收的运单号数量上限
+	waybillReturnBatchSize = 100
+)
+
+// waybillReturnEndpoint 电子面单单号回收接口
+var waybillReturnEndpoint = endpoint{
+	apiName:  "STO_WAYBILL_NO_RETURN",
+	toAppKey: "sto_waybill",
+	toCode:   "sto_waybill",
+
+	emptyFields: emptyFieldsOmit,
+}
+
+// WaybillReturnRequest 电子面单单号回收请求参数
+type WaybillReturnRequest struct {
+	WaybillNoList []string `json:"waybillNoList"` // 待回收的运单号列表
+	Reason        string   `json:"reason"`        // 回收原因
+}
+
+// Validate 验证请求参数
+func (r *WaybillReturnRequest) Validate() error {
+	if len(r.WaybillNoList) == 0 {
+		return fmt.Errorf("waybillNoList cannot be empty")
+	}
+	if len(r.WaybillNoList) > waybillReturnBatchSize {
+		return fmt.Errorf("waybillNoList cannot exceed %d items", waybillReturnBatchSize)
+	}
+	return nil
+}
+
+// WaybillReturnResult 单个运单号的回收结果
+type WaybillReturnResult struct {
+	WaybillNo string `json:"waybillNo"` // 运单号
+	Success   string `json:"success"`   // 是否回收成功
+	ErrorMsg  string `json:"errorMsg"`  // 失败原因
+}
+
+// WaybillReturnResponse 电子面单单号回收响应
+type WaybillReturnResponse struct {
+	BaseResponse
+	Data []WaybillReturnResult `json:"data"` // 各运单号的回收结果
+}
+
+// ReturnWaybillNos 将未使用的电子面单单号回收（作废）给申通
+func (c *Client) ReturnWaybillNos(ctx context.Context, req *WaybillReturnRequest) (*WaybillReturnResponse, error) {
+	// 验证请求参数
+	if err := req.Validate(); err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
+
+	res, err := c.execute(ctx, waybillReturnEndpoint, req)
+	if res == nil || res.resp == nil {
+		return nil, err
+	}
+
+	resp := &WaybillReturnResponse{BaseResponse: res.resp.BaseResponse}
+	if uerr := c.decodeData(res.resp, &resp.Data); uerr != nil {
+		return nil, uerr
+	}
+
+	return resp, err
+}
+
+// maxReclaimFailures 同一单号整批回收连续失败（网关繁忙和网络错误除外）的次数上限，超出后不再重试
+const maxReclaimFailures = 5
+
+// pendingReturn 已过期、等待回收的单号
+type pendingReturn struct {
+	waybillNo string
+	failures  int // 整批回收连续失败的次数，不含网关繁忙和网络错误
+}
+
+// pooledWaybillNo 运单号池中的单号
+type pooledWaybillNo struct {
+	waybillNo string
+	expireAt  time.Time
+}
+
+// WaybillNoPool 本地预取的电子面单单号池。单号超过有效期未被取用时，
+// 由Reclaim（或Run定时执行）自动回收给申通，可并发使用
+type WaybillNoPool struct {
+	client *Client
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	items   []pooledWaybillNo // 按放入顺序排列
+	pending []pendingReturn   // 已过期但回收失败、待下次重试的单号
+}
+
+// NewWaybillNoPool 创建运单号池，ttl<=0时使用DefaultWaybillNoTTL
+func NewWaybillNoPool(client *Client, ttl time.Duration) *WaybillNoPool {
+	if ttl <= 0 {
+		ttl = DefaultWaybillNoTTL
+	}
+	return &WaybillNoPool{client: client, ttl: ttl}
+}
+
+// Put 放入预取的运单号
+func (p *WaybillNoPool) Put(waybillNos ...string) {
+	expireAt := time.Now().Add(p.ttl)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, no := range waybillNos {
+		p.items = append(p.items, pooledWaybillNo{waybillNo: no, expireAt: expireAt})
+	}
+}
+
+// Take 取出最早放入且未过期的运单号
+func (p *WaybillNoPool) Take() (string, bool) {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.items) > 0 {
+		item := p.items[0]
+		p.items = p.items[1:]
+		if now.Before(item.expireAt) {
+			return item.waybillNo, true
+		}
+		p.pending = append(p.pending, pendingReturn{waybillNo: item.waybillNo})
+	}
+	return "", false
+}
+
+// Len 返回池中未过期的运单号数量
+func (p *WaybillNoPool) Len() int {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, item := range p.items {
+		if now.Before(item.expireAt) {
+			n++
+		}
+	}
+	return n
+}
+
+// ReclaimError 回收时被申通逐单拒绝，或整批回收连续失败maxReclaimFailures次的运单号。
+// 这些单号已从池中移除，不会再重试；其余整批回收失败的单号仍会在下次回收时重试
+type ReclaimError struct {
+	Rejected map[string]string // 不再重试的运单号及原因
+	Err      error             // 最后一个导致整批回收失败的错误，可能为nil
+}
+
+// Error 实现error接口
+func (e *ReclaimError) Error() string {
+	msg := fmt.Sprintf("%d waybill numbers rejected", len(e.Rejected))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回导致整批回收失败的错误
+func (e *ReclaimError) Unwrap() error {
+	return e.Err
+}
+
+// Reclaim 回收所有已过期的运单号，返回回收成功的单号。
+// 只有被申通逐单拒绝的单号立即放弃；整批回收失败（如签名错误、响应无法解析）的单号会在下次调用时重试，
+// 除网络错误和网关繁忙外连续失败maxReclaimFailures次后放弃。放弃的单号通过*ReclaimError返回
+func (p *WaybillNoPool) Reclaim(ctx context.Context) ([]string, error) {
+	now := time.Now()
+
+	p.mu.Lock()
+	expired := p.pending
+	p.pending = nil
+	kept := p.items[:0]
+	for _, item := range p.items {
+		if now.Before(item.expireAt) {
+			kept = append(kept, item)
+		} else {
+			expired = append(expired, pendingReturn{waybillNo: item.waybillNo})
+		}
+	}
+	p.items = kept
+	p.mu.Unlock()
+
+	var returned []string
+	var retry []pendingReturn
+	rejected := make(map[string]string)
+	var lastErr error
+	for start := 0; start < len(expired); start += waybillReturnBatchSize {
+		end := start + waybillReturnBatchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+		batch := expired[start:end]
+		nos := make([]string, len(batch))
+		for i, item := range batch {
+			nos[i] = item.waybillNo
+		}
+
+		ok, failed, err := p.returnBatch(ctx, nos)
+		if err != nil {
+			// 整批失败不说明单号本身不可回收，放回重试；只有非临时性的错误计入失败次数
+			lastErr = err
+			counted := ctx.Err() == nil && !isGatewayPressure(err)
+			for _, item := range batch {
+				if counted {
+					item.failures++
+				}
+				if item.failures >= maxReclaimFailures {
+					rejected[item.waybillNo] = err.Error()
+					continue
+				}
+				retry = append(retry, item)
+			}
+			continue
+		}
+		returned = append(returned, ok...)
+		done := make(map[string]bool, len(ok)+len(failed))
+		for _, no := range ok {
+			done[no] = true
+		}
+		for no, reason := range failed {
+			rejected[no] = reason
+			done[no] = true
+		}
+		for _, item := range batch {
+			if !done[item.waybillNo] {
+				retry = append(retry, pendingReturn{waybillNo: item.waybillNo})
+			}
+		}
+	}
+
+	if len(retry) > 0 {
+		p.mu.Lock()
+		p.pending = append(p.pending, retry...)
+		p.mu.Unlock()
+	}
+
+	if len(rejected) > 0 {
+		return returned, &ReclaimError{Rejected: rejected, Err: lastErr}
+	}
+	return returned, lastErr
+}
+
+// returnBatch 回收一批运单号，返回回收成功的单号和被逐单拒绝的单号及原因。
+// 未出现在逐单结果中的单号既不算成功也不算拒绝，由调用方放回重试
+func (p *WaybillNoPool) returnBatch(ctx context.Context, batch []string) ([]string, map[string]string, error) {
+	resp, err := p.client.ReturnWaybillNos(ctx, &WaybillReturnRequest{
+		WaybillNoList: batch,
+		Reason:        "expired",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := resp.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// 未返回逐单结果时视为整批成功
+	if len(resp.Data) == 0 {
+		return batch, nil, nil
+	}
+	var ok []string
+	failed := make(map[string]string)
+	for _, r := range resp.Data {
+		if r.Success == "true" {
+			ok = append(ok, r.WaybillNo)
+		} else {
+			failed[r.WaybillNo] = r.ErrorMsg
+		}
+	}
+	return ok, failed, nil
+}
+
+// Run 按interval定时回收过期运单号，直到ctx结束。onError为nil时忽略回收错误
+func (p *WaybillNoPool) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Reclaim(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
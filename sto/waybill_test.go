@@ -0,0 +1,183 @@
+package sto_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/maxbetas/sto-sdk-go/sto"
+	"github.com/maxbetas/sto-sdk-go/sto/stotest"
+)
+
+func TestWaybillNoPoolReclaim(t *testing.T) {
+	results := func(rs ...sto.WaybillReturnResult) stotest.HandlerFunc {
+		return func(json.RawMessage) (interface{}, error) { return rs, nil }
+	}
+	fail := func(code string, retry bool) stotest.HandlerFunc {
+		return func(json.RawMessage) (interface{}, error) {
+			return nil, &sto.APIError{ErrorCode: code, ErrorMsg: "failed", NeedRetry: retry}
+		}
+	}
+
+	tests := []struct {
+		name         string
+		handler      stotest.HandlerFunc
+		wantReturned []string
+		wantRejected []string
+		wantRetried  []string // 第二次回收时重新提交的单号
+	}{
+		{
+			name:         "whole batch returned",
+			handler:      results(),
+			wantReturned: []string{"A", "B"},
+		},
+		{
+			name: "per-item rejection is dropped",
+			handler: results(
+				sto.WaybillReturnResult{WaybillNo: "A", Success: "true"},
+				sto.WaybillReturnResult{WaybillNo: "B", Success: "false", ErrorMsg: "已使用"},
+			),
+			wantReturned: []string{"A"},
+			wantRejected: []string{"B"},
+		},
+		{
+			name:         "missing result is retried",
+			handler:      results(sto.WaybillReturnResult{WaybillNo: "A", Success: "true"}),
+			wantReturned: []string{"A"},
+			wantRetried:  []string{"B"},
+		},
+		{
+			name:        "system busy is retried",
+			handler:     fail(sto.ErrorCodeSystemBusy, true),
+			wantRetried: []string{"A", "B"},
+		},
+		{
+			name:        "whole batch rejection is retried",
+			handler:     fail(sto.ErrorCodeInvalidParam, false),
+			wantRetried: []string{"A", "B"},
+		},
+		{
+			name:        "whole batch signature error is retried",
+			handler:     fail(sto.ErrorCodeSignature, false),
+			wantRetried: []string{"A", "B"},
+		},
+		{
+			name: "undecodable response is retried",
+			handler: func(json.RawMessage) (interface{}, error) {
+				return "not a result list", nil
+			},
+			wantRetried: []string{"A", "B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			first := true
+			gw := stotest.NewGateway(testAppSecret)
+			gw.Handle("STO_WAYBILL_NO_RETURN", func(content json.RawMessage) (interface{}, error) {
+				var req sto.WaybillReturnRequest
+				if err := json.Unmarshal(content, &req); err != nil {
+					return nil, err
+				}
+				calls = append(calls, req.WaybillNoList)
+				if first {
+					first = false
+					return tt.handler(content)
+				}
+				return nil, nil
+			})
+			client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+			pool := sto.NewWaybillNoPool(client, time.Nanosecond)
+			pool.Put("A", "B")
+			time.Sleep(time.Millisecond)
+
+			returned, err := pool.Reclaim(context.Background())
+			if !equalSorted(returned, tt.wantReturned) {
+				t.Errorf("returned = %v, want %v", returned, tt.wantReturned)
+			}
+			var rerr *sto.ReclaimError
+			if errors.As(err, &rerr) {
+				var rejected []string
+				for no := range rerr.Rejected {
+					rejected = append(rejected, no)
+				}
+				if !equalSorted(rejected, tt.wantRejected) {
+					t.Errorf("rejected = %v, want %v", rejected, tt.wantRejected)
+				}
+			} else if len(tt.wantRejected) > 0 {
+				t.Errorf("err = %v, want *sto.ReclaimError", err)
+			}
+
+			if _, err := pool.Reclaim(context.Background()); err != nil {
+				t.Fatalf("second Reclaim: %v", err)
+			}
+			var retried []string
+			if len(calls) > 1 {
+				retried = calls[1]
+			}
+			if !equalSorted(retried, tt.wantRetried) {
+				t.Errorf("retried = %v, want %v", retried, tt.wantRetried)
+			}
+		})
+	}
+}
+
+func TestWaybillNoPoolReclaimGivesUpAfterRepeatedFailures(t *testing.T) {
+	var calls int
+	gw := stotest.NewGateway(testAppSecret)
+	gw.Handle("STO_WAYBILL_NO_RETURN", func(json.RawMessage) (interface{}, error) {
+		calls++
+		return nil, &sto.APIError{ErrorCode: sto.ErrorCodeSignature, ErrorMsg: "签名错误"}
+	})
+	client := newTestClient(t, gw, sto.WithMaxRetries(0))
+
+	pool := sto.NewWaybillNoPool(client, time.Nanosecond)
+	pool.Put("A", "B")
+	time.Sleep(time.Millisecond)
+
+	const maxFailures = 5
+	for i := 1; i < maxFailures; i++ {
+		_, err := pool.Reclaim(context.Background())
+		var rerr *sto.ReclaimError
+		if errors.As(err, &rerr) {
+			t.Fatalf("attempt %d: waybill numbers given up too early: %v", i, rerr.Rejected)
+		}
+		if sto.ErrorCodeOf(err) != sto.ErrorCodeSignature {
+			t.Fatalf("attempt %d: err = %v, want signature error", i, err)
+		}
+	}
+
+	_, err := pool.Reclaim(context.Background())
+	var rerr *sto.ReclaimError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("err = %v, want *sto.ReclaimError", err)
+	}
+	if len(rerr.Rejected) != 2 || !errors.Is(err, rerr.Err) || sto.ErrorCodeOf(rerr.Err) != sto.ErrorCodeSignature {
+		t.Errorf("ReclaimError = %+v, want A and B given up with the signature error", rerr)
+	}
+
+	if _, err := pool.Reclaim(context.Background()); err != nil {
+		t.Fatalf("Reclaim after giving up: %v", err)
+	}
+	if calls != maxFailures {
+		t.Errorf("gateway calls = %d, want %d", calls, maxFailures)
+	}
+}
+
+// equalSorted 忽略顺序比较两个字符串切片，nil与空切片视为相等
+func equalSorted(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}